@@ -0,0 +1,383 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/store"
+)
+
+// SchemaVersion 当前归档格式版本，归档内容或文件布局不兼容时递增
+//
+// 版本 2 增加了 ledger.jsonl 与工作流目录。
+const SchemaVersion = 2
+
+// MinSchemaVersion 仍可恢复的最低归档版本
+const MinSchemaVersion = 1
+
+// ManifestName 清单在归档中的文件名
+const ManifestName = "manifest.json"
+
+// configName 配置在归档中的文件名，统一保存为 JSON
+const configName = "config.json"
+
+// workflowPrefix 工作流定义在归档中的目录，恢复到恢复后配置的 workflow_dir
+const workflowPrefix = "workflows/"
+
+// 归档与单个文件的大小上限，防止解压炸弹
+const (
+	maxArchiveSize = 256 << 20
+	maxFileSize    = 128 << 20
+)
+
+var (
+	// ErrInvalidArchive 不是有效的备份归档
+	ErrInvalidArchive = errors.New("无效的备份归档")
+	// ErrSchemaVersion 归档版本不受支持
+	ErrSchemaVersion = errors.New("备份版本不受支持")
+	// ErrChecksum 文件校验和不匹配
+	ErrChecksum = errors.New("备份文件校验失败")
+)
+
+// FileEntry 清单中的文件记录
+type FileEntry struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest 归档清单
+type Manifest struct {
+	SchemaVersion int         `json:"schema_version"`
+	CreatedAt     time.Time   `json:"created_at"`
+	Host          string      `json:"host,omitempty"`
+	Encrypted     bool        `json:"encrypted"`
+	Files         []FileEntry `json:"files"`
+}
+
+// dataFile 数据目录中参与备份的文件
+type dataFile struct {
+	name     string
+	path     func(config.Settings) string
+	validate store.Validator
+}
+
+// dataFiles 归档名 → 数据文件，查询缓存可重新生成，不参与备份
+var dataFiles = []dataFile{
+	{"data/" + config.OutputFileName, config.Settings.OutputFile, store.ValidJSON},
+	{"data/" + config.JobsFileName, config.Settings.JobsFile, store.ValidJSON},
+	{"data/" + config.RewardsFileName, config.Settings.RewardsFile, store.ValidJSON},
+	{"data/" + config.SnapshotsFileName, config.Settings.SnapshotsFile, nil},
+	{"data/" + config.ShopFileName, config.Settings.ShopFile, nil},
+	{"data/" + config.LedgerFileName, config.Settings.LedgerFile, nil},
+}
+
+// Create 打包配置与数据目录中的状态文件并写入 w，passphrase 非空时加密
+func Create(w io.Writer, st config.Settings, passphrase string) (*Manifest, error) {
+	files := make(map[string][]byte)
+
+	cfg, err := config.ExportFile()
+	switch {
+	case err == nil:
+		files[configName] = cfg
+	case errors.Is(err, os.ErrNotExist):
+		slog.Warn("⚠️ 配置文件不存在，跳过", "file", config.ConfigFile)
+	default:
+		return nil, err
+	}
+
+	for _, f := range dataFiles {
+		path := f.path(st)
+		var data []byte
+		if f.validate != nil {
+			data, err = store.ReadFile(path, f.validate, store.DefaultBackups)
+		} else {
+			data, err = os.ReadFile(path)
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		files[f.name] = data
+	}
+
+	workflows, err := workflowFiles(st.WorkflowPath())
+	if err != nil {
+		return nil, err
+	}
+	for name, data := range workflows {
+		files[workflowPrefix+name] = data
+	}
+
+	host, _ := os.Hostname()
+	m := &Manifest{
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     clock.Now(),
+		Host:          host,
+		Encrypted:     passphrase != "",
+	}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		m.Files = append(m.Files, FileEntry{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])})
+	}
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+
+	var buf bytes.Buffer
+	if err := writeArchive(&buf, m, files); err != nil {
+		return nil, err
+	}
+	out := buf.Bytes()
+	if passphrase != "" {
+		if out, err = encrypt(out, passphrase); err != nil {
+			return nil, fmt.Errorf("加密备份失败: %w", err)
+		}
+	}
+	if _, err := w.Write(out); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// writeArchive 写出 tar.gz，清单位于第一项
+func writeArchive(w io.Writer, m *Manifest, files map[string][]byte) error {
+	manifest, err := sonic.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: m.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := add(ManifestName, manifest); err != nil {
+		return err
+	}
+	for _, f := range m.Files {
+		if err := add(f.Name, files[f.Name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Restore 校验归档并恢复其中的文件，dryRun 为 true 时只校验不写入
+//
+// 配置写回当前配置文件（按其格式转换）；数据文件与工作流写入恢复后配置的数据目录与工作流目录，归档中没有配置时使用 st。
+// 被覆盖的文件会轮转为 *.bak.1，可用 restore --file 回滚；归档中没有的文件保持不变。
+func Restore(r io.Reader, st config.Settings, passphrase string, dryRun bool) (*Manifest, error) {
+	data, err := io.ReadAll(io.LimitReader(r, maxArchiveSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取备份失败: %w", err)
+	}
+	if len(data) > maxArchiveSize {
+		return nil, fmt.Errorf("%w: 超过 %d MB", ErrInvalidArchive, maxArchiveSize>>20)
+	}
+	if isEncrypted(data) {
+		if data, err = decrypt(data, passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	m, files, err := readArchive(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先全部校验、转换，确认无误后再写入，避免只恢复了一部分
+	type write struct {
+		path string
+		data []byte
+	}
+	var writes []write
+	if content, ok := files[configName]; ok {
+		// 数据文件按恢复后的配置所指定的数据目录写入
+		out, restored, err := config.ImportFile(content)
+		if err != nil {
+			return nil, fmt.Errorf("备份中的配置无效: %w", err)
+		}
+		writes = append(writes, write{config.ConfigFile, out})
+		st = restored
+	}
+	for _, f := range m.Files {
+		content := files[f.Name]
+		if f.Name == configName {
+			continue
+		}
+		if name, ok := strings.CutPrefix(f.Name, workflowPrefix); ok {
+			if !isWorkflowFile(name) {
+				return nil, fmt.Errorf("%w: 非法的工作流文件 %s", ErrInvalidArchive, f.Name)
+			}
+			writes = append(writes, write{filepath.Join(st.WorkflowPath(), name), content})
+			continue
+		}
+		df, ok := lookupDataFile(f.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: 未知文件 %s", ErrInvalidArchive, f.Name)
+		}
+		if df.validate != nil {
+			if err := df.validate(content); err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidArchive, f.Name, err)
+			}
+		}
+		writes = append(writes, write{df.path(st), content})
+	}
+	if dryRun {
+		return m, nil
+	}
+
+	for _, w := range writes {
+		if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+			return nil, fmt.Errorf("创建目录失败: %w", err)
+		}
+		if err := store.WriteFile(w.path, w.data, 0644, store.DefaultBackups); err != nil {
+			return nil, fmt.Errorf("写入 %s 失败: %w", w.path, err)
+		}
+		slog.Info("♻️ 已从备份恢复", "file", w.path)
+	}
+	return m, nil
+}
+
+// readArchive 解压归档，校验清单版本与每个文件的大小和校验和
+func readArchive(data []byte) (*Manifest, map[string][]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	defer gz.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, nil, fmt.Errorf("%w: 不支持的条目 %s", ErrInvalidArchive, hdr.Name)
+		}
+		if _, dup := files[hdr.Name]; dup {
+			return nil, nil, fmt.Errorf("%w: 重复的条目 %s", ErrInvalidArchive, hdr.Name)
+		}
+		content, err := io.ReadAll(io.LimitReader(tr, maxFileSize+1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if len(content) > maxFileSize {
+			return nil, nil, fmt.Errorf("%w: %s 过大", ErrInvalidArchive, hdr.Name)
+		}
+		files[hdr.Name] = content
+	}
+
+	raw, ok := files[ManifestName]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: 缺少 %s", ErrInvalidArchive, ManifestName)
+	}
+	delete(files, ManifestName)
+	var m Manifest
+	if err := sonic.Unmarshal(raw, &m); err != nil {
+		return nil, nil, fmt.Errorf("%w: 清单解析失败: %v", ErrInvalidArchive, err)
+	}
+	if m.SchemaVersion > SchemaVersion {
+		return nil, nil, fmt.Errorf("%w: 备份版本 %d 高于当前支持的 %d，请先升级程序", ErrSchemaVersion, m.SchemaVersion, SchemaVersion)
+	}
+	if m.SchemaVersion < MinSchemaVersion {
+		return nil, nil, fmt.Errorf("%w: 备份版本 %d 过旧，最低支持 %d", ErrSchemaVersion, m.SchemaVersion, MinSchemaVersion)
+	}
+
+	listed := make(map[string]bool, len(m.Files))
+	for _, f := range m.Files {
+		content, ok := files[f.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: 缺少文件 %s", ErrChecksum, f.Name)
+		}
+		sum := sha256.Sum256(content)
+		if int64(len(content)) != f.Size || hex.EncodeToString(sum[:]) != f.SHA256 {
+			return nil, nil, fmt.Errorf("%w: %s", ErrChecksum, f.Name)
+		}
+		listed[f.Name] = true
+	}
+	for name := range files {
+		if !listed[name] {
+			return nil, nil, fmt.Errorf("%w: 清单中没有 %s", ErrInvalidArchive, name)
+		}
+	}
+	return &m, files, nil
+}
+
+// workflowFiles 读取工作流目录顶层的定义文件，与 workflow.Load 加载的范围一致，目录不存在时返回空
+func workflowFiles(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取工作流目录失败: %w", err)
+	}
+	files := make(map[string][]byte)
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !isWorkflowFile(e.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		files[e.Name()] = data
+	}
+	return files, nil
+}
+
+// isWorkflowFile 是否为工作流定义的文件名，不允许包含目录
+func isWorkflowFile(name string) bool {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func lookupDataFile(name string) (dataFile, bool) {
+	for _, f := range dataFiles {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return dataFile{}, false
+}
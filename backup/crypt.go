@@ -0,0 +1,102 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// encMagic 加密归档的文件头
+var encMagic = []byte("LLMAGET-ENC1\n")
+
+// scrypt 参数，解密时从同一组常量派生密钥
+const (
+	saltSize = 16
+	scryptN  = 1 << 15
+	scryptR  = 8
+	scryptP  = 1
+	keySize  = 32
+)
+
+var (
+	// ErrPassphraseRequired 归档已加密但未提供密码
+	ErrPassphraseRequired = errors.New("备份已加密，需要提供密码")
+	// ErrBadPassphrase 密码错误或加密数据被篡改
+	ErrBadPassphrase = errors.New("密码错误或备份已损坏")
+)
+
+// deriveKey 由密码和盐派生 AES-256 密钥
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keySize)
+}
+
+// encrypt 使用 AES-256-GCM 加密，输出格式为 文件头 | 盐 | nonce | 密文
+func encrypt(plain []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, len(encMagic)+len(salt)+len(nonce)+len(plain)+gcm.Overhead())
+	out = append(out, encMagic...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	// 文件头参与认证，防止被替换为其他格式
+	return gcm.Seal(out, nonce, plain, encMagic), nil
+}
+
+// decrypt 解密 encrypt 的输出
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, ErrPassphraseRequired
+	}
+	body := data[len(encMagic):]
+	if len(body) < saltSize {
+		return nil, ErrBadPassphrase
+	}
+	salt := body[:saltSize]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	body = body[saltSize:]
+	if len(body) < gcm.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+	nonce, sealed := body[:gcm.NonceSize()], body[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, encMagic)
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	return plain, nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, fmt.Errorf("派生密钥失败: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isEncrypted 判断是否为加密归档
+func isEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encMagic)
+}
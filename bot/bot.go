@@ -0,0 +1,188 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/jobs"
+	"llmaget/metrics"
+	"llmaget/services"
+)
+
+// commandTimeout 单条命令的执行时限，OneBot 的 HTTP 上报一般只等待数秒，超时的签到任务会在后台继续执行
+const commandTimeout = 20 * time.Second
+
+// command 机器人命令
+type command struct {
+	usage string
+	// mutating 为 true 的命令会修改账号状态，需配置 access_token、群显式开放且发送者为管理员
+	mutating bool
+	run      func(ctx context.Context, b *Bot, args []string) (string, error)
+}
+
+// Bot QQ 群机器人命令路由
+type Bot struct {
+	svc   *services.FF14Service
+	jobs  *jobs.Manager
+	state *config.AppState
+
+	commands map[string]command
+
+	mu       sync.Mutex
+	lastUsed map[string]time.Time
+}
+
+// New 创建机器人
+func New(svc *services.FF14Service, jobMgr *jobs.Manager) *Bot {
+	return &Bot{
+		svc:   svc,
+		jobs:  jobMgr,
+		state: config.GetState(),
+		commands: map[string]command{
+			"查":  {usage: "查 <角色名> [服务器] 查询角色的石之家 UUID", run: cmdSearch},
+			"时长": {usage: "时长 查看游戏时长", run: cmdPlayTime},
+			"奖励": {usage: "奖励 [2006-01] 查看签到奖励", run: cmdRewards},
+			"签到": {usage: "签到 签到并领取奖励", mutating: true, run: cmdSign},
+		},
+		lastUsed: make(map[string]time.Time),
+	}
+}
+
+// Handle 处理一条上报事件，返回回复内容，空字符串表示不回复
+func (b *Bot) Handle(ctx context.Context, ev *Event) string {
+	if ev.PostType != "message" {
+		return ""
+	}
+	cfg := b.state.Bot()
+	if cfg == nil {
+		return ""
+	}
+	name, args, ok := parseCommand(ev, cfg.CommandPrefix())
+	if !ok {
+		return ""
+	}
+
+	var group config.BotGroup
+	switch ev.MessageType {
+	case "group":
+		if group, ok = cfg.Group(ev.GroupID); !ok {
+			return ""
+		}
+	case "private":
+		if !cfg.IsAdmin(ev.UserID) {
+			return ""
+		}
+	default:
+		return ""
+	}
+
+	if name == "帮助" {
+		return b.help(ev, cfg, group)
+	}
+	cmd, ok := b.commands[name]
+	if !ok {
+		return ""
+	}
+
+	if !b.allowed(ev, cfg, group, name, cmd) {
+		metrics.BotCommands.WithLabelValues(name, "denied").Inc()
+		if cmd.mutating && b.enabled(ev, group, name, cmd) {
+			if cfg.AccessToken == "" {
+				return "⛔ 未配置 bot.access_token，" + cfg.CommandPrefix() + name + " 已停用"
+			}
+			return "⛔ 只有管理员可以使用 " + cfg.CommandPrefix() + name
+		}
+		return ""
+	}
+	if !b.take(ev, cfg, group, name) {
+		metrics.BotCommands.WithLabelValues(name, "cooldown").Inc()
+		slog.DebugContext(ctx, "命令冷却中，忽略", "command", name, "group", ev.GroupID, "user", ev.UserID)
+		return ""
+	}
+
+	slog.InfoContext(ctx, "🤖 机器人命令", "command", name, "args", args, "group", ev.GroupID, "user", ev.UserID)
+	runCtx, cancel := context.WithTimeout(ctx, commandTimeout)
+	defer cancel()
+	reply, err := cmd.run(runCtx, b, args)
+	if err != nil {
+		metrics.BotCommands.WithLabelValues(name, "error").Inc()
+		slog.WarnContext(ctx, "⚠️ 机器人命令失败", "command", name, "error", err)
+		if errors.Is(err, services.ErrCircuitOpen) {
+			return "❌ 石之家暂时无法访问，请稍后再试"
+		}
+		return "❌ " + err.Error()
+	}
+	metrics.BotCommands.WithLabelValues(name, "ok").Inc()
+	return reply
+}
+
+// enabled 命令是否在当前会话中开放（不考虑管理员身份）
+func (b *Bot) enabled(ev *Event, group config.BotGroup, name string, cmd command) bool {
+	if ev.MessageType == "private" {
+		return true
+	}
+	if len(group.Commands) == 0 {
+		return !cmd.mutating
+	}
+	return slices.Contains(group.Commands, name)
+}
+
+// allowed 发送者能否使用该命令
+func (b *Bot) allowed(ev *Event, cfg *config.BotConfig, group config.BotGroup, name string, cmd command) bool {
+	if !b.enabled(ev, group, name, cmd) {
+		return false
+	}
+	if !cmd.mutating {
+		return true
+	}
+	// 未配置 access_token 时上报无法鉴权，发送者与 sender.role 都可能被伪造
+	if cfg.AccessToken == "" {
+		return false
+	}
+	return cfg.IsAdmin(ev.UserID) || slices.Contains(group.Admins, ev.UserID) ||
+		ev.Sender.Role == "owner" || ev.Sender.Role == "admin"
+}
+
+// take 检查并记录冷却，同一群（私聊按用户）内同一命令在冷却期内只响应一次
+func (b *Bot) take(ev *Event, cfg *config.BotConfig, group config.BotGroup, name string) bool {
+	key := fmt.Sprintf("g%d:%s", ev.GroupID, name)
+	if ev.MessageType == "private" {
+		key = fmt.Sprintf("u%d:%s", ev.UserID, name)
+	}
+	now := clock.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if last, ok := b.lastUsed[key]; ok && now.Sub(last) < cfg.GroupCooldown(group) {
+		return false
+	}
+	b.lastUsed[key] = now
+	return true
+}
+
+// help 列出当前会话中发送者可用的命令
+func (b *Bot) help(ev *Event, cfg *config.BotConfig, group config.BotGroup) string {
+	names := make([]string, 0, len(b.commands))
+	for name := range b.commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sb strings.Builder
+	sb.WriteString("可用命令：")
+	for _, name := range names {
+		cmd := b.commands[name]
+		if b.allowed(ev, cfg, group, name, cmd) {
+			sb.WriteString("\n" + cfg.CommandPrefix() + cmd.usage)
+		}
+	}
+	return sb.String()
+}
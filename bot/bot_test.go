@@ -0,0 +1,203 @@
+package bot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+)
+
+// testConfig 群 100 开放查与签到，群 200 未配置命令；1 为全局管理员，2 为群 100 的管理员
+const testConfig = `{
+  "bot": {
+    "access_token": "tok",
+    "cooldown": "10s",
+    "admins": [1],
+    "groups": [
+      {"group_id": 100, "commands": ["查", "签到"], "admins": [2]},
+      {"group_id": 200}
+    ]
+  }
+}`
+
+// loadConfig 将 data 写入临时配置文件并加载
+func loadConfig(t *testing.T, data string) {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.ConfigFile
+	config.ConfigFile = file
+	t.Cleanup(func() { config.ConfigFile = old })
+	if err := config.GetState().Load(); err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+}
+
+// newTestBot 创建命令替换为桩实现的机器人，不访问石之家
+func newTestBot(t *testing.T, cfg string) *Bot {
+	t.Helper()
+	loadConfig(t, cfg)
+	b := New(nil, nil)
+	b.commands = map[string]command{
+		"查": {usage: "查 <角色名>", run: func(_ context.Context, _ *Bot, args []string) (string, error) {
+			return "查 " + strings.Join(args, ","), nil
+		}},
+		"签到": {usage: "签到", mutating: true, run: func(context.Context, *Bot, []string) (string, error) {
+			return "签到完成", nil
+		}},
+	}
+	return b
+}
+
+// groupMsg 构造群消息事件
+func groupMsg(group, user int64, role, text string) *Event {
+	ev := &Event{PostType: "message", MessageType: "group", GroupID: group, UserID: user, RawMessage: text}
+	ev.Sender.Role = role
+	return ev
+}
+
+// privateMsg 构造私聊消息事件
+func privateMsg(user int64, text string) *Event {
+	return &Event{PostType: "message", MessageType: "private", UserID: user, RawMessage: text}
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		msg    string
+		cmd    string
+		args   []string
+		ok     bool
+	}{
+		{"普通命令", "/", "/查 光之战士 拉诺西亚", "查", []string{"光之战士", "拉诺西亚"}, true},
+		{"前后空白", "/", "  /时长  ", "时长", []string{}, true},
+		{"全角前缀", "/", "／时长", "时长", []string{}, true},
+		{"开头 @机器人", "/", "[CQ:at,qq=10000] /查 a", "查", []string{"a"}, true},
+		{"回复并 @", "/", "[CQ:reply,id=5][CQ:at,qq=10000] /奖励 2026-01", "奖励", []string{"2026-01"}, true},
+		{"CQ 转义还原", "/", "/查 &#91;a&#93;&#44;b&amp;c", "查", []string{"[a],b&c"}, true},
+		{"命令不在开头", "/", "你好 /查 a", "", nil, false},
+		{"正文中的 CQ 码", "/", "你好[CQ:at,qq=1] /查", "", nil, false},
+		{"只有前缀", "/", "/", "", nil, false},
+		{"自定义前缀", "!", "!签到", "签到", []string{}, true},
+		{"自定义前缀不接受全角斜杠", "!", "／签到", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, args, ok := parseCommand(&Event{RawMessage: tt.msg}, tt.prefix)
+			if ok != tt.ok || cmd != tt.cmd || (ok && !slices.Equal(args, tt.args)) {
+				t.Errorf("parseCommand(%q) = %q %q %v, want %q %q %v", tt.msg, cmd, args, ok, tt.cmd, tt.args, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHandlePermissions(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *Event
+		want string
+	}{
+		{"群成员只读命令", groupMsg(100, 3, "member", "/查 a"), "查 a"},
+		{"群成员签到被拒", groupMsg(100, 3, "member", "/签到"), "⛔ 只有管理员可以使用 /签到"},
+		{"群配置的管理员签到", groupMsg(100, 2, "member", "/签到"), "签到完成"},
+		{"全局管理员签到", groupMsg(100, 1, "member", "/签到"), "签到完成"},
+		{"群管理员角色签到", groupMsg(100, 3, "admin", "/签到"), "签到完成"},
+		{"群主角色签到", groupMsg(100, 3, "owner", "/签到"), "签到完成"},
+		{"未开放签到的群", groupMsg(200, 1, "owner", "/签到"), ""},
+		{"未开放签到的群可用只读命令", groupMsg(200, 3, "member", "/查 b"), "查 b"},
+		{"未启用的群", groupMsg(300, 1, "owner", "/查 a"), ""},
+		{"管理员私聊", privateMsg(1, "/签到"), "签到完成"},
+		{"非管理员私聊", privateMsg(3, "/查 a"), ""},
+		{"未知命令", groupMsg(100, 1, "member", "/不存在"), ""},
+		{"非消息事件", &Event{PostType: "notice", MessageType: "group", GroupID: 100, RawMessage: "/查 a"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBot(t, testConfig)
+			if got := b.Handle(context.Background(), tt.ev); got != tt.want {
+				t.Errorf("Handle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleWithoutAccessToken(t *testing.T) {
+	b := newTestBot(t, strings.Replace(testConfig, `"access_token": "tok",`, "", 1))
+	ctx := context.Background()
+
+	// 未配置 access_token 时 role 与发送者都可能是伪造的，管理员也不能签到
+	for _, ev := range []*Event{
+		groupMsg(100, 1, "member", "/签到"),
+		groupMsg(100, 3, "admin", "/签到"),
+	} {
+		if got, want := b.Handle(ctx, ev), "⛔ 未配置 bot.access_token，/签到 已停用"; got != want {
+			t.Errorf("Handle(%d %s) = %q, want %q", ev.UserID, ev.Sender.Role, got, want)
+		}
+	}
+	if got := b.Handle(ctx, privateMsg(1, "/签到")); !strings.HasPrefix(got, "⛔") {
+		t.Errorf("私聊签到 = %q, 应被拒绝", got)
+	}
+	if got := b.Handle(ctx, groupMsg(100, 3, "member", "/查 a")); got != "查 a" {
+		t.Errorf("只读命令 = %q, want %q", got, "查 a")
+	}
+	if got := b.Handle(ctx, groupMsg(100, 1, "owner", "/帮助")); strings.Contains(got, "签到") {
+		t.Errorf("帮助不应列出签到: %q", got)
+	}
+}
+
+func TestHandleCooldown(t *testing.T) {
+	b := newTestBot(t, testConfig)
+	ctx := context.Background()
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, clock.Location())
+	defer clock.Set(clock.Fixed(start))()
+
+	if got := b.Handle(ctx, groupMsg(100, 3, "member", "/查 a")); got != "查 a" {
+		t.Fatalf("首次命令 = %q", got)
+	}
+	// 同一群内同一命令在冷却期内不回复，不区分发送者
+	if got := b.Handle(ctx, groupMsg(100, 4, "member", "/查 b")); got != "" {
+		t.Errorf("冷却期内 = %q, 应不回复", got)
+	}
+	// 其他群、其他命令不受影响
+	if got := b.Handle(ctx, groupMsg(200, 3, "member", "/查 c")); got != "查 c" {
+		t.Errorf("其他群 = %q", got)
+	}
+	// 被拒绝的命令不占用冷却
+	if got := b.Handle(ctx, groupMsg(100, 3, "member", "/签到")); !strings.HasPrefix(got, "⛔") {
+		t.Errorf("群成员签到 = %q, 应被拒绝", got)
+	}
+	if got := b.Handle(ctx, groupMsg(100, 1, "member", "/签到")); got != "签到完成" {
+		t.Errorf("其他命令 = %q", got)
+	}
+	if got := b.Handle(ctx, privateMsg(1, "/签到")); got != "签到完成" {
+		t.Errorf("私聊按用户冷却 = %q", got)
+	}
+
+	clock.Set(clock.Fixed(start.Add(10 * time.Second)))
+	if got := b.Handle(ctx, groupMsg(100, 4, "member", "/查 d")); got != "查 d" {
+		t.Errorf("冷却结束后 = %q", got)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	b := newTestBot(t, testConfig)
+	ctx := context.Background()
+
+	member := b.Handle(ctx, groupMsg(100, 3, "member", "/帮助"))
+	if !strings.Contains(member, "/查") || strings.Contains(member, "/签到") {
+		t.Errorf("群成员帮助 = %q", member)
+	}
+	admin := b.Handle(ctx, groupMsg(100, 2, "member", "/帮助"))
+	if !strings.Contains(admin, "/签到") {
+		t.Errorf("群管理员帮助 = %q", admin)
+	}
+}
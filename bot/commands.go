@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"llmaget/config"
+	"llmaget/jobs"
+	"llmaget/services"
+)
+
+// cmdSearch 查 <角色名> [服务器]
+func cmdSearch(ctx context.Context, b *Bot, args []string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "用法：查 <角色名> [服务器]", nil
+	}
+	name, server := args[0], ""
+	if len(args) == 2 {
+		server = args[1]
+	}
+	user, err := b.svc.SearchUser(ctx, name, server, false)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🔍 %s@%s（%s）\nUUID：%s", user.UserName, user.GroupName, user.AreaName, user.UUID), nil
+}
+
+// cmdPlayTime 时长
+func cmdPlayTime(_ context.Context, b *Bot, _ []string) (string, error) {
+	info, err := b.svc.ParseFFInfo()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("⏱️ %s 的游戏时长：%d 小时 %d 分钟", info.CharacterName, info.PlayTime/60, info.PlayTime%60), nil
+}
+
+// cmdRewards 奖励 [2006-01]
+func cmdRewards(ctx context.Context, b *Bot, args []string) (string, error) {
+	month := ""
+	if len(args) > 0 {
+		month = args[0]
+	}
+	month, err := services.NormalizeMonth(month)
+	if err != nil {
+		return "", err
+	}
+	rewards, err := b.svc.SignRewardList(ctx, month)
+	if err != nil {
+		return "", err
+	}
+	if rewards.Code != 10000 {
+		return "", fmt.Errorf("获取奖励列表失败: %s", rewards.Msg)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("🎁 " + month + " 签到奖励")
+	for _, r := range rewards.Data {
+		mark := "⬜"
+		switch r.IsGet {
+		case 0:
+			mark = "🟡"
+		case 1:
+			mark = "✅"
+		}
+		fmt.Fprintf(&sb, "\n%s %s ×%d（签到 %d 天）", mark, r.ItemName, r.Num, r.Rule)
+	}
+	sb.WriteString("\n✅ 已领取 🟡 可领取 ⬜ 未达成")
+	return sb.String(), nil
+}
+
+// cmdSign 签到，通过任务管理器执行，与定时签到、接口签到互相合并
+func cmdSign(ctx context.Context, b *Bot, _ []string) (string, error) {
+	job, _, err := b.jobs.Submit(ctx, jobs.KindSignAndClaim, config.DefaultAccount, jobs.TriggerBot)
+	if err != nil {
+		return "", fmt.Errorf("提交签到任务失败: %w", err)
+	}
+	finished, err := b.jobs.Wait(ctx, job.ID)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "⏳ 签到任务仍在执行，任务 " + job.ID, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if finished.Status == jobs.StatusFailed {
+		return "", fmt.Errorf("签到失败: %s", finished.Error)
+	}
+
+	result, _ := finished.Result.(map[string]any)
+	var sb strings.Builder
+	sb.WriteString("📝 签到完成")
+	for _, item := range []struct{ key, label string }{
+		{"success", "领取成功"},
+		{"fail", "领取失败"},
+		{"claimed", "此前已领取"},
+	} {
+		names := resultNames(result[item.key])
+		if len(names) > 0 {
+			fmt.Fprintf(&sb, "\n%s：%s", item.label, strings.Join(names, "、"))
+		}
+	}
+	return sb.String(), nil
+}
+
+// resultNames 将任务结果中的名称列表转换为字符串切片
+func resultNames(v any) []string {
+	list, _ := v.([]any)
+	names := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			names = append(names, s)
+		}
+	}
+	return names
+}
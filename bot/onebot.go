@@ -0,0 +1,255 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"llmaget/logging"
+	"llmaget/models"
+)
+
+// Event OneBot v11 上报事件，只解析命令路由需要的字段
+type Event struct {
+	PostType    string `json:"post_type"`
+	MessageType string `json:"message_type"`
+	SelfID      int64  `json:"self_id"`
+	MessageID   int64  `json:"message_id"`
+	UserID      int64  `json:"user_id"`
+	GroupID     int64  `json:"group_id"`
+	RawMessage  string `json:"raw_message"`
+	Sender      struct {
+		Nickname string `json:"nickname"`
+		Role     string `json:"role"`
+	} `json:"sender"`
+}
+
+// action OneBot v11 API 调用
+type action struct {
+	Action string `json:"action"`
+	Params any    `json:"params"`
+	Echo   string `json:"echo,omitempty"`
+}
+
+// actionResponse OneBot v11 API 响应
+type actionResponse struct {
+	Status  string `json:"status"`
+	Retcode int    `json:"retcode"`
+	Echo    string `json:"echo"`
+}
+
+// cqCode 消息中的 CQ 码
+var cqCode = regexp.MustCompile(`\[CQ:[^\]]*\]`)
+
+// parseCommand 从消息中解析命令名与参数，开头 @机器人 的 CQ 码会被忽略
+func parseCommand(ev *Event, prefix string) (string, []string, bool) {
+	text := strings.TrimSpace(ev.RawMessage)
+	// 回复、@ 等 CQ 码只在开头出现时跳过，避免误把正文中的内容当作命令
+	for strings.HasPrefix(text, "[CQ:") {
+		loc := cqCode.FindStringIndex(text)
+		if loc == nil || loc[0] != 0 {
+			break
+		}
+		text = strings.TrimSpace(text[loc[1]:])
+	}
+	text = unescapeCQ(text)
+
+	rest, ok := strings.CutPrefix(text, prefix)
+	if !ok && prefix == "/" {
+		rest, ok = strings.CutPrefix(text, "／")
+	}
+	if !ok {
+		return "", nil, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// unescapeCQ 还原 CQ 码转义
+func unescapeCQ(s string) string {
+	return strings.NewReplacer("&#91;", "[", "&#93;", "]", "&#44;", ",", "&amp;", "&").Replace(s)
+}
+
+// RegisterRoutes 注册 OneBot 上报地址
+//
+// HTTP POST 上报使用快速操作回复；反向 WebSocket 通过 send_group_msg / send_private_msg 回复。
+func (b *Bot) RegisterRoutes(r *gin.Engine) {
+	g := r.Group("/llmaget/onebot")
+	{
+		g.POST("/http", b.HandleHTTP)
+		g.GET("/ws", b.HandleWebSocket)
+	}
+}
+
+// HandleHTTP 处理 HTTP POST 上报，配置了 access_token 时校验 X-Signature
+func (b *Bot) HandleHTTP(c *gin.Context) {
+	cfg := b.state.Bot()
+	if cfg == nil {
+		c.JSON(http.StatusNotFound, models.NewError(404, "机器人未启用"))
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "读取上报失败"))
+		return
+	}
+	if cfg.AccessToken != "" && !validSignature(cfg.AccessToken, body, c.GetHeader("X-Signature")) {
+		c.JSON(http.StatusUnauthorized, models.NewError(401, "签名校验失败"))
+		return
+	}
+
+	var ev Event
+	if err := sonic.Unmarshal(body, &ev); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "上报格式错误"))
+		return
+	}
+	reply := b.Handle(c.Request.Context(), &ev)
+	if reply == "" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reply": reply, "auto_escape": true, "at_sender": false})
+}
+
+// validSignature 校验 HTTP 上报签名：X-Signature: sha1=<HMAC-SHA1(access_token, body)>
+func validSignature(secret string, body []byte, header string) bool {
+	got, ok := strings.CutPrefix(header, "sha1=")
+	if !ok {
+		return false
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	want := hex.EncodeToString(mac.Sum(nil))
+	return subtle.ConstantTimeCompare([]byte(got), []byte(want)) == 1
+}
+
+var upgrader = websocket.Upgrader{
+	// OneBot 实现不是浏览器，不发送 Origin，鉴权依赖 access_token
+	CheckOrigin: func(*http.Request) bool { return true },
+}
+
+// HandleWebSocket 接受 OneBot 实现的反向 WebSocket 连接（Universal 角色）
+func (b *Bot) HandleWebSocket(c *gin.Context) {
+	cfg := b.state.Bot()
+	if cfg == nil {
+		c.JSON(http.StatusNotFound, models.NewError(404, "机器人未启用"))
+		return
+	}
+	if cfg.AccessToken != "" && !validToken(c, cfg.AccessToken) {
+		c.JSON(http.StatusUnauthorized, models.NewError(401, "access_token 无效"))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "⚠️ WebSocket 握手失败", "error", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	s := &wsSession{conn: conn}
+	defer conn.Close()
+
+	selfID := c.GetHeader("X-Self-ID")
+	slog.InfoContext(ctx, "🔌 OneBot 已连接", "self_id", selfID, "remote", c.ClientIP())
+	defer slog.InfoContext(ctx, "🔌 OneBot 已断开", "self_id", selfID)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.WarnContext(ctx, "⚠️ OneBot 连接读取失败", "error", err)
+			}
+			return
+		}
+
+		var probe struct {
+			PostType string  `json:"post_type"`
+			Echo     *string `json:"echo"`
+		}
+		if err := sonic.Unmarshal(data, &probe); err != nil {
+			slog.WarnContext(ctx, "⚠️ OneBot 消息格式错误", "error", err)
+			continue
+		}
+		if probe.Echo != nil {
+			var resp actionResponse
+			if sonic.Unmarshal(data, &resp) == nil && resp.Status == "failed" {
+				slog.WarnContext(ctx, "⚠️ OneBot 发送消息失败", "echo", resp.Echo, "retcode", resp.Retcode)
+			}
+			continue
+		}
+		if probe.PostType != "message" {
+			continue
+		}
+
+		var ev Event
+		if err := sonic.Unmarshal(data, &ev); err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			evCtx := logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+			if reply := b.Handle(evCtx, &ev); reply != "" {
+				if err := s.reply(&ev, reply); err != nil {
+					slog.WarnContext(evCtx, "⚠️ 回复消息失败", "error", err)
+				}
+			}
+		}()
+	}
+}
+
+// validToken 校验反向 WebSocket 的 Authorization: Bearer 令牌或 access_token 查询参数
+func validToken(c *gin.Context, token string) bool {
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		got = c.Query("access_token")
+	}
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// wsSession 单个反向 WebSocket 连接，写入需串行
+type wsSession struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+	seq  atomic.Int64
+}
+
+// reply 按消息来源回复到群或私聊
+func (s *wsSession) reply(ev *Event, text string) error {
+	a := action{Echo: "llmaget-" + strconv.FormatInt(s.seq.Add(1), 10)}
+	if ev.MessageType == "group" {
+		a.Action = "send_group_msg"
+		a.Params = map[string]any{"group_id": ev.GroupID, "message": text, "auto_escape": true}
+	} else {
+		a.Action = "send_private_msg"
+		a.Params = map[string]any{"user_id": ev.UserID, "message": text, "auto_escape": true}
+	}
+	data, err := sonic.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn.WriteMessage(websocket.TextMessage, data)
+}
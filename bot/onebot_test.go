@@ -0,0 +1,190 @@
+package bot
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// newTestServer 启动挂载 OneBot 上报地址的测试服务
+func newTestServer(t *testing.T, b *Bot) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	b.RegisterRoutes(r)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// sign 按 OneBot HTTP 上报规则计算 X-Signature
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(body)
+	return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postEvent 模拟 OneBot 实现的 HTTP POST 上报，signature 为空时不签名
+func postEvent(t *testing.T, url string, ev *Event, signature func([]byte) string) (int, map[string]any) {
+	t.Helper()
+	body, err := sonic.Marshal(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodPost, url+"/llmaget/onebot/http", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if signature != nil {
+		req.Header.Set("X-Signature", signature(body))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]any
+	if resp.StatusCode != http.StatusNoContent {
+		if err := sonic.ConfigDefault.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+func TestHandleHTTP(t *testing.T) {
+	srv := newTestServer(t, newTestBot(t, testConfig))
+	valid := func(body []byte) string { return sign("tok", body) }
+
+	tests := []struct {
+		name      string
+		ev        *Event
+		signature func([]byte) string
+		status    int
+		reply     string
+	}{
+		{"签名正确", groupMsg(100, 3, "member", "[CQ:at,qq=10000] /查 a b"), valid, http.StatusOK, "查 a,b"},
+		{"管理员签到", groupMsg(100, 2, "member", "/签到"), valid, http.StatusOK, "签到完成"},
+		{"非命令消息", groupMsg(100, 3, "member", "大家好"), valid, http.StatusNoContent, ""},
+		{"缺少签名", groupMsg(100, 3, "owner", "/签到"), nil, http.StatusUnauthorized, ""},
+		{"签名错误", groupMsg(100, 3, "owner", "/签到"), func(b []byte) string { return sign("wrong", b) }, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postEvent(t, srv.URL, tt.ev, tt.signature)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d (%v)", status, tt.status, body)
+			}
+			if tt.reply != "" && body["reply"] != tt.reply {
+				t.Errorf("reply = %v, want %q", body["reply"], tt.reply)
+			}
+		})
+	}
+}
+
+func TestHandleHTTPWithoutAccessToken(t *testing.T) {
+	srv := newTestServer(t, newTestBot(t, strings.Replace(testConfig, `"access_token": "tok",`, "", 1)))
+
+	// 未签名的上报只能使用只读命令，伪造的 role 无法触发签到
+	status, body := postEvent(t, srv.URL, groupMsg(100, 3, "admin", "/签到"), nil)
+	if status != http.StatusOK || !strings.HasPrefix(body["reply"].(string), "⛔") {
+		t.Errorf("伪造管理员签到 = %d %v, 应被拒绝", status, body)
+	}
+	status, body = postEvent(t, srv.URL, groupMsg(100, 3, "member", "/查 a"), nil)
+	if status != http.StatusOK || body["reply"] != "查 a" {
+		t.Errorf("只读命令 = %d %v", status, body)
+	}
+}
+
+func TestHandleHTTPDisabled(t *testing.T) {
+	srv := newTestServer(t, newTestBot(t, `{}`))
+	if status, _ := postEvent(t, srv.URL, groupMsg(100, 1, "owner", "/查 a"), nil); status != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", status)
+	}
+}
+
+// dialBot 模拟 OneBot 实现以反向 WebSocket 连接机器人
+func dialBot(t *testing.T, srv *httptest.Server, header http.Header) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/llmaget/onebot/ws"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+// readAction 读取机器人发出的下一个 API 调用
+func readAction(t *testing.T, conn *websocket.Conn) map[string]any {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("读取 API 调用失败: %v", err)
+	}
+	var a map[string]any
+	if err := sonic.Unmarshal(data, &a); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestHandleWebSocket(t *testing.T) {
+	srv := newTestServer(t, newTestBot(t, testConfig))
+
+	for _, header := range []http.Header{nil, {"Authorization": {"Bearer wrong"}}} {
+		if _, resp, err := dialBot(t, srv, header); err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("令牌 %v 应被拒绝: %v", header, err)
+		}
+	}
+
+	conn, _, err := dialBot(t, srv, http.Header{"Authorization": {"Bearer tok"}, "X-Self-ID": {"10000"}})
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	send := func(v any) {
+		data, _ := sonic.Marshal(v)
+		if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// API 响应、心跳与无需回复的消息都不会产生调用
+	send(map[string]any{"status": "failed", "retcode": 100, "echo": "llmaget-0"})
+	send(map[string]any{"post_type": "meta_event", "meta_event_type": "heartbeat"})
+	send(groupMsg(100, 3, "member", "/签到"))
+	a := readAction(t, conn)
+	params, _ := a["params"].(map[string]any)
+	if a["action"] != "send_group_msg" || params["group_id"] != float64(100) || params["message"] != "⛔ 只有管理员可以使用 /签到" {
+		t.Errorf("群消息回复 = %v", a)
+	}
+
+	send(groupMsg(100, 3, "member", "你好 /查 a"))
+	send(privateMsg(1, "/查 b"))
+	a = readAction(t, conn)
+	params, _ = a["params"].(map[string]any)
+	if a["action"] != "send_private_msg" || params["user_id"] != float64(1) || params["message"] != "查 b" {
+		t.Errorf("私聊回复 = %v", a)
+	}
+	if echo, _ := a["echo"].(string); !strings.HasPrefix(echo, "llmaget-") {
+		t.Errorf("echo = %q", echo)
+	}
+}
+
+func TestHandleWebSocketQueryToken(t *testing.T) {
+	srv := newTestServer(t, newTestBot(t, testConfig))
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/llmaget/onebot/ws?access_token=tok"
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("access_token 查询参数应被接受: %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,150 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"golang.org/x/sync/singleflight"
+
+	"llmaget/models"
+	"llmaget/store"
+)
+
+// entry 缓存条目，值以 JSON 保存以便落盘
+type entry struct {
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt time.Time       `json:"expires_at"`
+}
+
+// Cache 带过期时间的缓存，可选持久化到磁盘
+//
+// 相同 key 的并发未命中请求会合并为一次加载。
+type Cache struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	file    string
+
+	group  singleflight.Group
+	hits   atomic.Int64
+	misses atomic.Int64
+	shared atomic.Int64
+}
+
+// New 创建缓存，file 非空时从文件恢复未过期的条目，并在写入后落盘
+func New(file string) *Cache {
+	c := &Cache{entries: make(map[string]entry), file: file}
+	if file == "" {
+		return c
+	}
+
+	_, err := store.ReadFile(file, func(data []byte) error {
+		clear(c.entries)
+		return sonic.Unmarshal(data, &c.entries)
+	}, store.DefaultBackups)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("⚠️ 缓存文件读取失败，忽略", "file", file, "error", err)
+		clear(c.entries)
+	}
+	c.pruneLocked(time.Now())
+	return c
+}
+
+// Get 读取未过期的缓存并解码到 dst
+func (c *Cache) Get(key string, dst any) bool {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok || time.Now().After(e.ExpiresAt) {
+		return false
+	}
+	return sonic.Unmarshal(e.Value, dst) == nil
+}
+
+// Set 写入缓存
+func (c *Cache) Set(key string, v any, ttl time.Duration) {
+	data, err := sonic.Marshal(v)
+	if err != nil {
+		slog.Warn("⚠️ 缓存编码失败", "key", key, "error", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	c.pruneLocked(now)
+	c.entries[key] = entry{Value: data, ExpiresAt: now.Add(ttl)}
+	c.persistLocked()
+}
+
+// Stats 获取缓存统计
+func (c *Cache) Stats() models.CacheStats {
+	c.mu.Lock()
+	n := len(c.entries)
+	c.mu.Unlock()
+	return models.CacheStats{
+		Entries: n,
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Shared:  c.shared.Load(),
+	}
+}
+
+// pruneLocked 清理过期条目（调用方需持有锁）
+func (c *Cache) pruneLocked(now time.Time) {
+	for k, e := range c.entries {
+		if now.After(e.ExpiresAt) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// persistLocked 将缓存写入文件（调用方需持有锁）
+func (c *Cache) persistLocked() {
+	if c.file == "" {
+		return
+	}
+	data, err := sonic.Marshal(c.entries)
+	if err != nil {
+		slog.Warn("⚠️ 缓存编码失败", "error", err)
+		return
+	}
+	if err := store.WriteFile(c.file, data, 0644, store.DefaultBackups); err != nil {
+		slog.Warn("⚠️ 保存缓存失败", "file", c.file, "error", err)
+	}
+}
+
+// Do 读取缓存，未命中或 refresh 为 true 时调用 load 加载并写入缓存
+//
+// 同一 key 的并发加载只执行一次，其余调用方共享结果；加载使用首个调用方的 ctx。
+// load 返回错误时不缓存。
+func Do[V any](ctx context.Context, c *Cache, key string, ttl time.Duration, refresh bool, load func(ctx context.Context) (V, error)) (V, error) {
+	var v V
+	if !refresh && c.Get(key, &v) {
+		c.hits.Add(1)
+		return v, nil
+	}
+	c.misses.Add(1)
+
+	res, err, shared := c.group.Do(key, func() (any, error) {
+		v, err := load(ctx)
+		if err != nil {
+			return v, err
+		}
+		c.Set(key, v, ttl)
+		return v, nil
+	})
+	if shared {
+		c.shared.Add(1)
+	}
+	if err != nil {
+		return v, err
+	}
+	return res.(V), nil
+}
@@ -0,0 +1,1100 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/backup"
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/cookies"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/logging"
+	"llmaget/mcpserver"
+	"llmaget/models"
+	"llmaget/recap"
+	"llmaget/services"
+	"llmaget/shop"
+	"llmaget/sites"
+	"llmaget/sites/ff14"
+	"llmaget/store"
+	"llmaget/tools"
+	"llmaget/workflow"
+)
+
+// 输出格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// errUsage 参数错误，退出码为 2
+var errUsage = errors.New("参数错误")
+
+// globalOptions 所有子命令共用的参数
+type globalOptions struct {
+	configPath string
+	output     string
+	account    string
+	sets       setFlags
+}
+
+// setFlags 可重复的 --set key=value 参数
+type setFlags map[string]string
+
+func (f setFlags) String() string {
+	parts := make([]string, 0, len(f))
+	for k, v := range f {
+		parts = append(parts, k+"="+v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f setFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("格式应为 key=value")
+	}
+	f[key] = val
+	return nil
+}
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, opts *globalOptions, args []string) error
+}
+
+var commands = []command{
+	{"serve", "启动 HTTP 服务与定时任务（默认）", cmdServe},
+	{"sign", "签到并领取所有可领取的奖励，失败时返回非零退出码", cmdSign},
+	{"info", "获取当前登录角色的基础信息", cmdInfo},
+	{"sites", "列出已接入的站点并检查登录凭据是否有效", cmdSites},
+	{"search", "search [--refresh] <角色名> [服务器] 搜索用户的石之家 UUID", cmdSearch},
+	{"rewards", "rewards [--month 2006-01] [--claim|--history] 查看、领取签到奖励或查看本地奖励历史", cmdRewards},
+	{"config", "config set-cookie [cookie] 设置 Cookie，省略时从标准输入读取；config import-cookie [文件] 从 cookies.txt、HAR、EditThisCookie JSON 或 Cookie 请求头导入并检查会话；config validate 校验并输出生效配置", cmdConfig},
+	{"missions", "missions [--run] 查看每日社区任务，--run 完成已开启动作的任务并领取积分", cmdMissions},
+	{"shop", "shop [--notify] 检查积分余额与积分商城商品并记录，--notify 将关注商品的补货或可兑换提醒通过通知渠道发送", cmdShop},
+	{"recap", "recap [--from 2006-01-02 --to 2006-01-02] [--send] 生成周报，默认统计上一个自然周，--send 通过通知渠道发送", cmdRecap},
+	{"workflow", "workflow list 列出工作流；workflow run [--dry-run] <名称> 执行工作流，--dry-run 只发送 GET 请求", cmdWorkflow},
+	{"mcp", "以 stdio 方式运行 MCP 服务，供本地 LLM 客户端调用", cmdMCP},
+	{"export", "export [--format csv|jsonl|xlsx] [--from 2006-01-02] [--to 2006-01-02] [--out 文件] <snapshots|ledger|rewards|watchlist> 导出数据，默认输出到标准输出", cmdExport},
+	{"backup", "backup [--out 文件] [--passphrase-file 文件] 将配置与状态数据打包为 tar.gz 备份，提供密码时加密", cmdBackup},
+	{"restore", "restore [--file config|response] [--backup 1] [--list] 从备份恢复状态文件；restore [--dry-run] [--passphrase-file 文件] <归档> 从 backup 归档恢复，需在服务停止时执行", cmdRestore},
+}
+
+// runCLI 解析命令行并执行子命令，返回进程退出码
+func runCLI(args []string) int {
+	opts := &globalOptions{
+		configPath: config.ConfigFile,
+		output:     outputTable,
+		account:    config.DefaultAccount,
+		sets:       setFlags{},
+	}
+	root := newFlagSet("llmaget", opts)
+	root.Usage = func() { printUsage(root.Output()) }
+	if err := root.Parse(args); err != nil {
+		return 2
+	}
+
+	name := "serve"
+	rest := root.Args()
+	if len(rest) > 0 {
+		name, rest = rest[0], rest[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == name {
+			cmd = &commands[i]
+			break
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	if cmd.name != "serve" && os.Getenv(logging.EnvLogLevel) == "" {
+		// 单次命令默认只输出警告以上日志，避免干扰结果
+		logging.Setup(os.Getenv(logging.EnvLogFormat), "warn")
+	} else {
+		logging.SetupFromEnv()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = logging.NewContext(ctx)
+
+	if err := cmd.run(ctx, opts, rest); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet 创建带有全局参数的 FlagSet，全局参数可写在子命令前后，
+// 默认值取自 opts 当前值，因此子命令不会覆盖写在前面的全局参数
+func newFlagSet(name string, opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&opts.configPath, "config", opts.configPath, "配置文件路径")
+	fs.StringVar(&opts.output, "output", opts.output, "输出格式: table 或 json")
+	fs.StringVar(&opts.account, "account", opts.account, "账号名")
+	fs.Var(opts.sets, "set", "覆盖配置项 key=value，可重复，如 --set server_port=:9090")
+	return fs
+}
+
+// parseCommandFlags 解析子命令参数、应用全局参数并加载配置
+func parseCommandFlags(fs *flag.FlagSet, opts *globalOptions, args []string) ([]string, error) {
+	if err := parseGlobalFlags(fs, opts, args); err != nil {
+		return nil, err
+	}
+	state := config.GetState()
+	if err := state.Load(); err != nil {
+		return nil, err
+	}
+
+	st := state.Settings()
+	if os.Getenv(logging.EnvLogLevel) == "" && state.Effective().Sources["log_level"] == config.SourceDefault {
+		// 单次命令默认只输出警告以上日志，避免干扰结果
+		logging.Setup(st.LogFormat, "warn")
+	} else {
+		logging.Setup(st.LogFormat, st.LogLevel)
+	}
+	return fs.Args(), nil
+}
+
+// parseGlobalFlags 解析参数并校验全局参数
+func parseGlobalFlags(fs *flag.FlagSet, opts *globalOptions, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if opts.output != outputTable && opts.output != outputJSON {
+		return fmt.Errorf("%w: 不支持的输出格式 %s", errUsage, opts.output)
+	}
+	if opts.account != config.DefaultAccount {
+		return fmt.Errorf("%w: 暂只支持 %s 账号", errUsage, config.DefaultAccount)
+	}
+
+	flagSettings, err := config.ParseSettings(opts.sets)
+	if err != nil {
+		return fmt.Errorf("%w: --set %v", errUsage, err)
+	}
+
+	config.ConfigFile = opts.configPath
+	config.GetState().SetFlagSettings(flagSettings)
+	return nil
+}
+
+// printUsage 输出帮助信息
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "FF14 捡垃圾助手")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "用法: llmaget [--config 路径] [--output table|json] [--account 账号] [--set key=value] <命令> [参数]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "命令:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.usage)
+	}
+}
+
+func cmdServe(_ context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("serve", opts)
+	if err := parseGlobalFlags(fs, opts, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: serve 不接受位置参数", errUsage)
+	}
+
+	// 配置由 runServe 加载，配置无效时仍以默认配置启动
+	if code := runServe(); code != 0 {
+		return errors.New("服务异常退出")
+	}
+	return nil
+}
+
+func cmdSign(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("sign", opts)
+	if _, err := parseCommandFlags(fs, opts, args); err != nil {
+		return err
+	}
+
+	svc := services.NewFF14Service()
+	ledger := jobs.NewLedger(config.GetState().Settings().LedgerFile())
+	var body []byte
+	_, err := ledger.Run(ctx, jobs.KindSignAndClaim, config.DefaultAccount, jobs.TriggerManual, func(ctx context.Context) (any, error) {
+		var err error
+		body, err = svc.SignAndGetSignReward(ctx)
+		return sites.JSONResult(body, err)
+	})
+	if err != nil {
+		return fmt.Errorf("签到并领取奖励失败: %w", err)
+	}
+	return renderClaimResult(opts, body)
+}
+
+// cmdMCP 通过标准输入输出提供 MCP 服务，标准输出只用于协议消息，日志写入标准错误
+func cmdMCP(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("mcp", opts)
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: mcp 不接受位置参数", errUsage)
+	}
+	return mcpserver.RunStdio(ctx, tools.NewRegistry(services.NewFF14Service(), nil), func() tools.Allowlist {
+		return tools.Allowlist(config.GetState().Settings().AgentToolList())
+	})
+}
+
+func cmdInfo(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("info", opts)
+	if _, err := parseCommandFlags(fs, opts, args); err != nil {
+		return err
+	}
+
+	v, err := ff14.New(services.NewFF14Service()).FetchInfo(ctx)
+	if err != nil {
+		return err
+	}
+	info := v.(*ff14.Info)
+
+	return render(opts, info, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "角色\t%s\n", info.CharacterName)
+		fmt.Fprintf(tw, "UUID\t%s\n", info.UUID)
+		fmt.Fprintf(tw, "大区\t%s\n", info.AreaName)
+		fmt.Fprintf(tw, "服务器\t%s\n", info.GroupName)
+		fmt.Fprintf(tw, "游戏时长\t%s\n", info.PlayTime)
+	})
+}
+
+// cmdSites 列出站点并检查各站点的登录凭据
+func cmdSites(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("sites", opts)
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: sites 不接受位置参数", errUsage)
+	}
+
+	reg := newSiteRegistry(services.NewFF14Service())
+	for _, a := range reg.All() {
+		reg.CheckSession(ctx, a)
+	}
+	status := reg.Status()
+	return render(opts, status, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "站点\t名称\t启用\t凭据\t会话\t任务")
+		for _, st := range status {
+			session := "有效"
+			if !st.Session.Valid {
+				session = st.Session.Error
+			}
+			kinds := make([]string, 0, len(st.Tasks))
+			for _, t := range st.Tasks {
+				kinds = append(kinds, t.Kind)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\t%s\n", st.Name, st.Title, st.Enabled, st.Credentials.Hint, session, strings.Join(kinds, ","))
+		}
+	})
+}
+
+func cmdSearch(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("search", opts)
+	refresh := fs.Bool("refresh", false, "跳过缓存，重新搜索")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 || len(rest) > 2 {
+		return fmt.Errorf("%w: 用法 search <角色名> [服务器]", errUsage)
+	}
+
+	name, server := rest[0], ""
+	if len(rest) == 2 {
+		server = rest[1]
+	}
+
+	user, err := services.NewFF14Service().SearchUser(ctx, name, server, *refresh)
+	if err != nil {
+		return err
+	}
+
+	return render(opts, user, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "角色\t%s\n", user.UserName)
+		fmt.Fprintf(tw, "UUID\t%s\n", user.UUID)
+		fmt.Fprintf(tw, "大区\t%s\n", user.AreaName)
+		fmt.Fprintf(tw, "服务器\t%s\n", user.GroupName)
+	})
+}
+
+func cmdRewards(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("rewards", opts)
+	month := fs.String("month", "", "奖励月份，格式 2006-01，默认当月")
+	claim := fs.Bool("claim", false, "领取该月所有可领取的奖励")
+	history := fs.Bool("history", false, "查看本地保存的奖励历史，不请求接口")
+	if _, err := parseCommandFlags(fs, opts, args); err != nil {
+		return err
+	}
+	if *claim && *history {
+		return fmt.Errorf("%w: --claim 与 --history 不能同时使用", errUsage)
+	}
+	if _, err := services.NormalizeMonth(*month); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	svc := services.NewFF14Service()
+	switch {
+	case *claim:
+		return rewardsClaim(ctx, opts, svc, *month)
+	case *history:
+		return rewardsHistory(opts, svc, *month)
+	}
+
+	rewards, err := svc.SignRewardList(ctx, *month)
+	if err != nil {
+		return err
+	}
+	if rewards.Code != 10000 {
+		return fmt.Errorf("获取奖励列表失败: %d %s", rewards.Code, rewards.Msg)
+	}
+
+	return render(opts, rewards.Data, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\t奖励\t数量\t签到天数\t状态")
+		for _, r := range rewards.Data {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%s\n", r.ID, r.ItemName, r.Num, r.Rule, rewardStatus(r.IsGet))
+		}
+	})
+}
+
+// rewardsClaim 领取指定月份的奖励
+func rewardsClaim(ctx context.Context, opts *globalOptions, svc *services.FF14Service, month string) error {
+	body, err := svc.ClaimRewards(ctx, month)
+	if err != nil {
+		return fmt.Errorf("领取奖励失败: %w", err)
+	}
+	return renderClaimResult(opts, body)
+}
+
+// renderClaimResult 输出奖励领取结果，extra 为额外输出的字段，有领取失败时返回错误
+func renderClaimResult(opts *globalOptions, body []byte, extra ...string) error {
+	var result map[string][]string
+	if err := sonic.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析结果失败: %w", err)
+	}
+	if err := render(opts, result, func(tw *tabwriter.Writer) {
+		for _, key := range append([]string{"success", "fail", "claimed", "unavailable"}, extra...) {
+			fmt.Fprintf(tw, "%s\t%s\n", key, strings.Join(result[key], ", "))
+		}
+	}); err != nil {
+		return err
+	}
+
+	if len(result["fail"]) > 0 {
+		return fmt.Errorf("%d 个奖励领取失败", len(result["fail"]))
+	}
+	return nil
+}
+
+// rewardsHistory 输出本地保存的奖励历史，month 为空时输出全部月份
+func rewardsHistory(opts *globalOptions, svc *services.FF14Service, month string) error {
+	var months []models.RewardMonth
+	if month == "" {
+		list, err := svc.RewardHistory().List()
+		if err != nil {
+			return err
+		}
+		months = list
+	} else {
+		m, ok, err := svc.RewardHistory().Month(month)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%s 没有奖励记录", month)
+		}
+		months = []models.RewardMonth{*m}
+	}
+
+	return render(opts, months, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "月份\tID\t奖励\t数量\t签到天数\t状态\t领取时间")
+		for _, m := range months {
+			for _, r := range m.Rewards {
+				claimedAt := ""
+				if r.ClaimedAt != nil {
+					claimedAt = clock.Format(*r.ClaimedAt)
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%d\t%s\t%s\n", m.Month, r.ID, r.ItemName, r.Num, r.Rule, rewardStatus(r.IsGet), claimedAt)
+			}
+		}
+	})
+}
+
+func cmdConfig(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("config", opts)
+	if err := parseGlobalFlags(fs, opts, args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return fmt.Errorf("%w: 用法 config set-cookie [cookie] | config import-cookie [文件] | config validate", errUsage)
+	}
+
+	// 允许全局参数写在二级子命令之后
+	sub := newFlagSet("config "+rest[0], opts)
+	if err := parseGlobalFlags(sub, opts, rest[1:]); err != nil {
+		return err
+	}
+
+	switch rest[0] {
+	case "validate":
+		if sub.NArg() > 0 {
+			return fmt.Errorf("%w: 用法 config validate", errUsage)
+		}
+		return configValidate(opts)
+	case "set-cookie":
+		return configSetCookie(sub.Args())
+	case "import-cookie":
+		return configImportCookie(ctx, opts, sub.Args())
+	default:
+		return fmt.Errorf("%w: 未知的 config 子命令 %s", errUsage, rest[0])
+	}
+}
+
+// configValidate 校验配置文件、环境变量与命令行覆盖，并输出生效配置
+func configValidate(opts *globalOptions) error {
+	if _, err := os.Stat(config.ConfigFile); err != nil {
+		return fmt.Errorf("配置文件不可读: %w", err)
+	}
+	if err := config.GetState().Load(); err != nil {
+		return err
+	}
+
+	eff := config.GetState().Effective()
+	if err := render(opts, eff, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "config_file	%s	\n", eff.ConfigFile)
+		fmt.Fprintf(tw, "user_agent	%s	\n", eff.UserAgent)
+		fmt.Fprintf(tw, "cookie	%s	\n", eff.Cookie)
+		values := settingsMap(eff.Settings)
+		for _, key := range config.SettingKeys() {
+			fmt.Fprintf(tw, "%s	%v	%s\n", key, values[key], eff.Sources[key])
+		}
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "✅ 配置有效")
+	return nil
+}
+
+// configSetCookie 写入 Cookie
+func configSetCookie(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%w: 用法 config set-cookie [cookie]", errUsage)
+	}
+	if err := config.GetState().Load(); err != nil {
+		return err
+	}
+
+	cookie := ""
+	if len(args) == 1 {
+		cookie = args[0]
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("读取标准输入失败: %w", err)
+		}
+		cookie = line
+	}
+	cookie = strings.TrimSpace(cookie)
+	if cookie == "" {
+		return fmt.Errorf("%w: cookie 不能为空", errUsage)
+	}
+
+	if err := config.GetState().SetConfig(config.Config{Cookie: cookie}); err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	fmt.Printf("✅ Cookie 已写入 %s\n", config.ConfigFile)
+	return nil
+}
+
+// configImportCookie 从浏览器导出的文件中提取 ff14risingstones 写入配置，并检查会话
+func configImportCookie(ctx context.Context, opts *globalOptions, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%w: 用法 config import-cookie [文件]", errUsage)
+	}
+	var data []byte
+	var err error
+	if len(args) == 0 || args[0] == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("读取导入内容失败: %w", err)
+	}
+	if err := config.GetState().Load(); err != nil {
+		return err
+	}
+
+	imp, err := cookies.Extract(data, config.Current().BaseURL)
+	if err != nil {
+		return err
+	}
+	res, err := cookies.Apply(ctx, imp, newSiteRegistry(services.NewFF14Service()))
+	if err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	if err := render(opts, res, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "格式\t%s\n", res.Format)
+		if res.Domain != "" {
+			fmt.Fprintf(tw, "域名\t%s\n", res.Domain)
+		}
+		if res.Expires != nil {
+			left := res.Expires.Sub(clock.Now())
+			if left >= 24*time.Hour {
+				fmt.Fprintf(tw, "过期时间\t%s（剩余 %d 天）\n", clock.Format(*res.Expires), int(left.Hours()/24))
+			} else {
+				fmt.Fprintf(tw, "过期时间\t%s（剩余 %s）\n", clock.Format(*res.Expires), left.Round(time.Minute))
+			}
+		} else {
+			fmt.Fprintln(tw, "过期时间\t未提供")
+		}
+		fmt.Fprintf(tw, "Cookie 总数\t%d\n", res.Found)
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✅ Cookie 已写入 %s\n", config.ConfigFile)
+	if !res.Session.Valid {
+		return fmt.Errorf("会话检查未通过: %s", res.Session.Error)
+	}
+	fmt.Fprintln(os.Stderr, "✅ 会话有效")
+	return nil
+}
+
+func cmdRestore(_ context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("restore", opts)
+	file := fs.String("file", "config", "要恢复的文件: config 或 response")
+	backup := fs.Int("backup", 1, "备份序号，1 为最新")
+	list := fs.Bool("list", false, "仅列出可用备份")
+	dryRun := fs.Bool("dry-run", false, "恢复归档时只校验不写入")
+	passFile := fs.String("passphrase-file", "", "归档密码文件，默认读取 "+envBackupPassphrase)
+	if err := parseGlobalFlags(fs, opts, args); err != nil {
+		return err
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		return restoreArchive(fs.Arg(0), *passFile, *dryRun)
+	default:
+		return fmt.Errorf("%w: 用法 restore [--dry-run] [--passphrase-file 文件] <归档>", errUsage)
+	}
+
+	var path string
+	validate := store.ValidJSON
+	switch *file {
+	case "config":
+		// 不加载配置，配置文件本身可能就是要恢复的对象
+		path, validate = config.ConfigFile, config.ValidateFile
+	case "response":
+		state := config.GetState()
+		if err := state.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️ %v，按默认数据目录查找\n", err)
+		}
+		path = state.Settings().OutputFile()
+	default:
+		return fmt.Errorf("%w: 不支持的文件 %s", errUsage, *file)
+	}
+
+	if *list {
+		type backupInfo struct {
+			Backup  int    `json:"backup"`
+			Path    string `json:"path"`
+			Size    int64  `json:"size"`
+			ModTime string `json:"mod_time"`
+			Valid   bool   `json:"valid"`
+		}
+		var infos []backupInfo
+		for _, n := range store.Backups(path, store.DefaultBackups) {
+			p := store.BackupPath(path, n)
+			fi, err := os.Stat(p)
+			if err != nil {
+				continue
+			}
+			data, err := os.ReadFile(p)
+			infos = append(infos, backupInfo{
+				Backup:  n,
+				Path:    p,
+				Size:    fi.Size(),
+				ModTime: clock.Format(fi.ModTime()),
+				Valid:   err == nil && validate(data) == nil,
+			})
+		}
+		return render(opts, infos, func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "序号\t修改时间\t大小\t可用\t路径")
+			for _, b := range infos {
+				fmt.Fprintf(tw, "%d\t%s\t%d\t%v\t%s\n", b.Backup, b.ModTime, b.Size, b.Valid, b.Path)
+			}
+		})
+	}
+
+	if *backup < 1 || *backup > store.DefaultBackups {
+		return fmt.Errorf("%w: --backup 需在 1 到 %d 之间", errUsage, store.DefaultBackups)
+	}
+	if err := store.Restore(path, *backup, validate, store.DefaultBackups); err != nil {
+		return fmt.Errorf("恢复失败: %w", err)
+	}
+	fmt.Printf("✅ 已用备份 %d 恢复 %s，原文件已轮转为备份 1\n", *backup, path)
+	return nil
+}
+
+func cmdExport(_ context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("export", opts)
+	format := fs.String("format", export.FormatCSV, "导出格式: csv、jsonl 或 xlsx")
+	from := fs.String("from", "", "起始日期（含），格式 2006-01-02 或 RFC3339")
+	to := fs.String("to", "", "结束日期（含当天），格式 2006-01-02 或 RFC3339")
+	out := fs.String("out", "", "输出文件，默认标准输出")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("%w: 用法 export [--format csv|jsonl|xlsx] <%s>", errUsage, strings.Join(export.Datasets(), "|"))
+	}
+	name := rest[0]
+	if err := export.Lookup(name); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	filter, err := export.ParseFilter(*from, *to, opts.account)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	var dst io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("创建输出文件失败: %w", err)
+		}
+		defer f.Close()
+		dst = f
+	}
+	w, err := export.NewWriter(*format, dst)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	rows, err := export.Export(w, localSources(), name, filter)
+	if err != nil {
+		return fmt.Errorf("导出失败: %w", err)
+	}
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "✅ 已导出 %d 行到 %s\n", rows, *out)
+	}
+	return nil
+}
+
+// localSources 从数据目录读取快照、奖励、签到流水与积分记录，服务运行时也可安全读取
+func localSources() export.Sources {
+	st := config.GetState().Settings()
+	return export.Sources{
+		Snapshots: services.NewSnapshotLog(st.SnapshotsFile()),
+		Rewards:   services.NewRewardHistory(st.RewardsFile()),
+		Ledger:    jobs.NewLedger(st.LedgerFile()),
+		Shop:      services.NewShopLog(st.ShopFile()),
+		Watch:     st.ShopWatchList(),
+	}
+}
+
+func cmdRecap(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("recap", opts)
+	from := fs.String("from", "", "起始日期（含），默认上一个自然周")
+	to := fs.String("to", "", "结束日期（含当天）")
+	send := fs.Bool("send", false, "通过通知渠道发送")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: 用法 recap [--from 2006-01-02 --to 2006-01-02] [--send]", errUsage)
+	}
+	start, end := recap.LastWeek(clock.Now())
+	if *from != "" || *to != "" {
+		filter, err := export.ParseFilter(*from, *to, opts.account)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errUsage, err)
+		}
+		if filter.From.IsZero() || filter.To.IsZero() {
+			return fmt.Errorf("%w: --from 与 --to 需同时提供", errUsage)
+		}
+		start, end = filter.From, filter.To
+	}
+
+	recaps, err := recap.Generate(ctx, localSources(), start, end)
+	if err != nil {
+		return fmt.Errorf("生成周报失败: %w", err)
+	}
+	if len(recaps) == 0 {
+		return errors.New("统计区间内没有快照数据")
+	}
+	err = render(opts, recaps, func(tw *tabwriter.Writer) {
+		for i, r := range recaps {
+			if i > 0 {
+				fmt.Fprintln(tw)
+			}
+			fmt.Fprintln(tw, r.Text)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if *send {
+		n, err := recap.Deliver(ctx, recaps)
+		if err != nil {
+			return fmt.Errorf("发送周报失败（成功 %d 条）: %w", n, err)
+		}
+		fmt.Fprintf(os.Stderr, "✅ 已发送 %d 条通知\n", n)
+	}
+	return nil
+}
+
+// cmdMissions 查看或执行每日社区任务
+func cmdMissions(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("missions", opts)
+	run := fs.Bool("run", false, "完成 mission_actions 中开启的动作并领取任务积分")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: 用法 missions [--run]", errUsage)
+	}
+
+	svc := services.NewFF14Service()
+	if *run {
+		body, err := svc.DoMissions(ctx)
+		if err != nil {
+			return fmt.Errorf("执行社区任务失败: %w", err)
+		}
+		return renderClaimResult(opts, body, "actions")
+	}
+
+	list, err := svc.MissionList(ctx)
+	if err != nil {
+		return err
+	}
+	if list.Code != 10000 {
+		return fmt.Errorf("获取社区任务失败: %d %s", list.Code, list.Msg)
+	}
+	return render(opts, list.Data, func(tw *tabwriter.Writer) {
+		fmt.Fprintln(tw, "ID\t任务\t类型\t进度\t积分\t状态")
+		for _, m := range list.Data {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%d/%d\t%d\t%s\n", m.ID, m.Name, m.Type, m.FinishNum, m.TargetNum, m.Points, missionStatus(m.Status))
+		}
+	})
+}
+
+// cmdShop 检查积分余额与积分商城商品
+func cmdShop(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("shop", opts)
+	send := fs.Bool("notify", false, "将关注商品的提醒通过通知渠道发送")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: 用法 shop [--notify]", errUsage)
+	}
+
+	svc := services.NewFF14Service()
+	res, err := shop.Check(ctx, svc)
+	if err != nil {
+		return fmt.Errorf("检查积分商城失败: %w", err)
+	}
+	err = render(opts, res, func(tw *tabwriter.Writer) {
+		change := ""
+		if res.Change != nil {
+			change = fmt.Sprintf("（较上次 %+d）", *res.Change)
+		}
+		fmt.Fprintf(tw, "当前积分\t%d%s\n\n", res.Points, change)
+		fmt.Fprintln(tw, "ID\t商品\t积分\t库存\t关注\t可兑换")
+		for _, it := range res.Items {
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%t\t%t\n", it.ID, it.Name, it.Cost, it.Stock, it.Watched, it.Affordable)
+		}
+		if len(res.Alerts) > 0 {
+			fmt.Fprintf(tw, "\n%s\n", shop.Render(res.Alerts))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if len(res.Alerts) == 0 {
+		return shop.Save(svc, res)
+	}
+	// 提醒未送达前不保存记录，留给下次检查或定时任务重新提醒
+	if !*send {
+		fmt.Fprintln(os.Stderr, "ℹ️ 提醒未发送，本次记录未保存；使用 --notify 发送")
+		return nil
+	}
+	n, err := shop.Deliver(ctx, res.Alerts)
+	if err != nil && n == 0 {
+		return fmt.Errorf("发送提醒失败，本次记录未保存: %w", err)
+	}
+	if saveErr := shop.Save(svc, res); saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return fmt.Errorf("发送提醒失败（成功 %d 条）: %w", n, err)
+	}
+	fmt.Fprintf(os.Stderr, "✅ 已发送 %d 条通知\n", n)
+	return nil
+}
+
+// cmdWorkflow 列出或执行工作流
+func cmdWorkflow(ctx context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("workflow", opts)
+	if err := parseGlobalFlags(fs, opts, args); err != nil {
+		return err
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return fmt.Errorf("%w: 用法 workflow list | workflow run [--dry-run] <名称>", errUsage)
+	}
+
+	sub := newFlagSet("workflow "+rest[0], opts)
+	dryRun := sub.Bool("dry-run", false, "演练：只发送 GET 请求，其余请求记为跳过")
+	subArgs, err := parseCommandFlags(sub, opts, rest[1:])
+	if err != nil {
+		return err
+	}
+
+	dir := config.Current().WorkflowPath()
+	defs, loadErr := workflow.Load(dir)
+	switch rest[0] {
+	case "list":
+		if len(subArgs) > 0 {
+			return fmt.Errorf("%w: 用法 workflow list", errUsage)
+		}
+		if err := render(opts, defs, func(tw *tabwriter.Writer) {
+			fmt.Fprintln(tw, "任务类型\t名称\t调度\t文件")
+			for _, d := range defs {
+				schedule := "manual"
+				if d.Every != "" {
+					schedule = "every " + d.Every
+				} else if d.Daily != "" {
+					schedule = "daily " + d.Daily
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", d.Kind(), d.Title, schedule, d.File)
+			}
+		}); err != nil {
+			return err
+		}
+		if loadErr != nil {
+			return fmt.Errorf("部分工作流定义无效: %w", loadErr)
+		}
+		if len(defs) == 0 {
+			fmt.Fprintf(os.Stderr, "目录 %s 下没有工作流定义\n", dir)
+		}
+		return nil
+	case "run":
+		if len(subArgs) != 1 {
+			return fmt.Errorf("%w: 用法 workflow run [--dry-run] <名称>", errUsage)
+		}
+		name := strings.TrimPrefix(subArgs[0], workflow.KindPrefix)
+		idx := slices.IndexFunc(defs, func(d *workflow.Definition) bool { return d.Name == name })
+		if idx < 0 {
+			if loadErr != nil {
+				return fmt.Errorf("未找到工作流 %s，部分定义无效: %w", name, loadErr)
+			}
+			return fmt.Errorf("未找到工作流 %s（目录 %s）", name, dir)
+		}
+		result, err := defs[idx].Run(ctx, services.NewFF14Service(), *dryRun)
+		if err != nil {
+			return fmt.Errorf("执行工作流失败: %w", err)
+		}
+		if err := render(opts, result, func(tw *tabwriter.Writer) {
+			fmt.Fprintf(tw, "success\t%s\n", strings.Join(result.Success, ", "))
+			fmt.Fprintf(tw, "fail\t%s\n", strings.Join(result.Fail, ", "))
+			fmt.Fprintf(tw, "skipped\t%s\n", strings.Join(result.Skipped, ", "))
+			for _, k := range slices.Sorted(maps.Keys(result.Outputs)) {
+				fmt.Fprintf(tw, "%s\t%s\n", k, result.Outputs[k])
+			}
+		}); err != nil {
+			return err
+		}
+		if len(result.Fail) > 0 {
+			return fmt.Errorf("%d 项执行失败", len(result.Fail))
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: 未知的 workflow 子命令 %s", errUsage, rest[0])
+	}
+}
+
+// envBackupPassphrase 备份密码环境变量，避免密码出现在命令行历史中
+const envBackupPassphrase = config.EnvPrefix + "BACKUP_PASSPHRASE"
+
+// readPassphrase 读取备份密码，优先使用密码文件，其次为环境变量
+func readPassphrase(file string) (string, error) {
+	if file == "" {
+		return os.Getenv(envBackupPassphrase), nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("读取密码文件失败: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+func cmdBackup(_ context.Context, opts *globalOptions, args []string) error {
+	fs := newFlagSet("backup", opts)
+	out := fs.String("out", "", "输出文件，默认 llmaget-<时间>.tar.gz（加密时追加 .enc）")
+	passFile := fs.String("passphrase-file", "", "加密密码文件，默认读取 "+envBackupPassphrase+"，均为空时不加密")
+	rest, err := parseCommandFlags(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: backup 不接受位置参数", errUsage)
+	}
+	passphrase, err := readPassphrase(*passFile)
+	if err != nil {
+		return err
+	}
+
+	path := *out
+	if path == "" {
+		path = "llmaget-" + clock.Now().Format("20060102-150405") + ".tar.gz"
+		if passphrase != "" {
+			path += ".enc"
+		}
+	}
+	var buf bytes.Buffer
+	m, err := backup.Create(&buf, config.GetState().Settings(), passphrase)
+	if err != nil {
+		return fmt.Errorf("创建备份失败: %w", err)
+	}
+	if err := store.WriteFile(path, buf.Bytes(), 0600, 0); err != nil {
+		return fmt.Errorf("写入备份失败: %w", err)
+	}
+
+	return render(opts, m, func(tw *tabwriter.Writer) {
+		fmt.Fprintf(tw, "备份文件\t%s\n", path)
+		fmt.Fprintf(tw, "版本\t%d\n", m.SchemaVersion)
+		fmt.Fprintf(tw, "加密\t%v\n", m.Encrypted)
+		for _, f := range m.Files {
+			fmt.Fprintf(tw, "%s\t%d 字节\n", f.Name, f.Size)
+		}
+	})
+}
+
+// restoreArchive 从 backup 生成的归档恢复配置与状态数据
+func restoreArchive(path, passFile string, dryRun bool) error {
+	passphrase, err := readPassphrase(passFile)
+	if err != nil {
+		return err
+	}
+	state := config.GetState()
+	if err := state.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️ %v，按默认数据目录恢复\n", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("打开备份失败: %w", err)
+	}
+	defer f.Close()
+	m, err := backup.Restore(f, state.Settings(), passphrase, dryRun)
+	if err != nil {
+		return fmt.Errorf("恢复失败: %w", err)
+	}
+
+	action := "已恢复"
+	if dryRun {
+		action = "校验通过，未写入"
+	}
+	fmt.Printf("✅ %s %s（版本 %d，%s 创建于 %s）\n", action, path, m.SchemaVersion, m.Host, clock.Format(m.CreatedAt))
+	for _, e := range m.Files {
+		fmt.Printf("  %s\t%d 字节\n", e.Name, e.Size)
+	}
+	if !dryRun {
+		fmt.Println("被覆盖的文件已轮转为 *.bak.1，可用 restore --file 回滚")
+	}
+	return nil
+}
+
+// settingsMap 将运行参数转换为 key → 值
+func settingsMap(st config.Settings) map[string]any {
+	data, _ := sonic.Marshal(st)
+	values := make(map[string]any)
+	_ = sonic.Unmarshal(data, &values)
+	return values
+}
+
+// render 按输出格式打印结果
+func render(opts *globalOptions, data any, table func(tw *tabwriter.Writer)) error {
+	if opts.output == outputJSON {
+		out, err := sonic.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(tw)
+	return tw.Flush()
+}
+
+// rewardStatus 奖励领取状态描述
+func rewardStatus(isGet int) string {
+	switch isGet {
+	case 0:
+		return "可领取"
+	case 1:
+		return "已领取"
+	default:
+		return "未达成"
+	}
+}
+
+// missionStatus 社区任务状态的中文描述
+func missionStatus(status int) string {
+	switch status {
+	case models.MissionCompleted:
+		return "待领取"
+	case models.MissionClaimed:
+		return "已领取"
+	default:
+		return "未完成"
+	}
+}
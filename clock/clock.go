@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// 内置时区数据，精简容器镜像中没有 zoneinfo 时也能加载 Asia/Shanghai
+	_ "time/tzdata"
+)
+
+// DefaultTimezone 游戏业务时区，奖励月份、签到日期均以此为准
+const DefaultTimezone = "Asia/Shanghai"
+
+// DisplayLayout 面向用户展示的时间格式
+const DisplayLayout = "2006-01-02 15:04:05"
+
+// Clock 时间来源，测试时可替换为固定时间
+type Clock interface {
+	Now() time.Time
+}
+
+// System 系统时钟
+type System struct{}
+
+// Now 返回系统当前时间
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed 始终返回固定时间的时钟
+type Fixed time.Time
+
+// Now 返回固定时间
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
+
+var (
+	mu       sync.RWMutex
+	current  Clock = System{}
+	location       = mustLoadLocation(DefaultTimezone)
+)
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// LoadLocation 按 IANA 名称加载时区
+func LoadLocation(name string) (*time.Location, error) {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("未知时区 %s: %w", name, err)
+	}
+	return loc, nil
+}
+
+// Set 替换时间来源，返回恢复原时钟的函数
+func Set(c Clock) (restore func()) {
+	mu.Lock()
+	prev := current
+	current = c
+	mu.Unlock()
+	return func() {
+		mu.Lock()
+		current = prev
+		mu.Unlock()
+	}
+}
+
+// SetLocation 设置业务时区
+func SetLocation(loc *time.Location) {
+	mu.Lock()
+	defer mu.Unlock()
+	location = loc
+}
+
+// Location 当前业务时区
+func Location() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
+	return location
+}
+
+// Now 业务时区下的当前时间
+func Now() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.Now().In(location)
+}
+
+// In 将 t 转换到业务时区
+func In(t time.Time) time.Time {
+	return t.In(Location())
+}
+
+// Format 按 DisplayLayout 在业务时区下格式化，零值返回空字符串
+func Format(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return In(t).Format(DisplayLayout)
+}
+
+// FormatRFC3339 按 RFC3339 在业务时区下格式化，零值返回空字符串
+func FormatRFC3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return In(t).Format(time.RFC3339)
+}
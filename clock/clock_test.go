@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNowUsesBusinessTimezone(t *testing.T) {
+	// UTC 16:30 已是上海的次日 00:30
+	restore := Set(Fixed(time.Date(2026, 1, 31, 16, 30, 0, 0, time.UTC)))
+	defer restore()
+
+	now := Now()
+	if got, want := now.Format("2006-01-02 15:04"), "2026-02-01 00:30"; got != want {
+		t.Fatalf("Now() = %s, want %s", got, want)
+	}
+	if now.Location().String() != DefaultTimezone {
+		t.Fatalf("Now() location = %s, want %s", now.Location(), DefaultTimezone)
+	}
+}
+
+func TestSetRestore(t *testing.T) {
+	fixed := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+	restore := Set(Fixed(fixed))
+	if !Now().Equal(fixed) {
+		t.Fatalf("Now() = %s, want %s", Now(), fixed)
+	}
+	restore()
+	if Now().Equal(fixed) {
+		t.Fatal("restore 后仍返回固定时间")
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+		rfc  string
+	}{
+		{"零值", time.Time{}, "", ""},
+		{"UTC 跨日", time.Date(2026, 3, 31, 16, 0, 0, 0, time.UTC), "2026-04-01 00:00:00", "2026-04-01T00:00:00+08:00"},
+		{"上海午夜前", time.Date(2026, 3, 31, 15, 59, 59, 0, time.UTC), "2026-03-31 23:59:59", "2026-03-31T23:59:59+08:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.in); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+			if got := FormatRFC3339(tt.in); got != tt.rfc {
+				t.Errorf("FormatRFC3339() = %q, want %q", got, tt.rfc)
+			}
+		})
+	}
+}
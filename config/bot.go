@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// DefaultBotCooldown 机器人命令默认冷却时间
+const DefaultBotCooldown = 10 * time.Second
+
+// BotConfig QQ 群机器人配置（OneBot v11），未配置时机器人不可用
+//
+// 只响应 Groups 中列出的群；私聊只响应 Admins。
+type BotConfig struct {
+	// AccessToken 反向 WebSocket 的 Bearer 令牌，同时作为 HTTP POST 上报的签名密钥；
+	// 未配置时上报无法鉴权，签到等修改类命令一律拒绝
+	AccessToken string `json:"access_token,omitempty"`
+	// Prefix 命令前缀，默认 "/"
+	Prefix string `json:"prefix,omitempty"`
+	// Cooldown 同一群内同一命令的冷却时间，默认 10s
+	Cooldown Duration `json:"cooldown,omitempty"`
+	// Admins 可在任意群和私聊中使用全部命令的 QQ 号
+	Admins []int64 `json:"admins,omitempty"`
+	// Groups 启用机器人的群
+	Groups []BotGroup `json:"groups,omitempty"`
+}
+
+// BotGroup 单个群的权限配置
+type BotGroup struct {
+	GroupID int64 `json:"group_id"`
+	// Commands 本群可用的命令（不含前缀），为空时只开放只读命令；签到等修改类命令还需配置 access_token 且发送者为管理员
+	Commands []string `json:"commands,omitempty"`
+	// Admins 本群的管理员 QQ 号，群主与群管理员也视为管理员
+	Admins []int64 `json:"admins,omitempty"`
+	// Cooldown 覆盖全局冷却时间
+	Cooldown Duration `json:"cooldown,omitempty"`
+}
+
+// Validate 校验机器人配置
+func (b *BotConfig) Validate() error {
+	if b.Cooldown < 0 {
+		return fmt.Errorf("%w: bot.cooldown 不能为负数", ErrInvalidConfig)
+	}
+	seen := make(map[int64]bool, len(b.Groups))
+	for _, g := range b.Groups {
+		if g.GroupID <= 0 {
+			return fmt.Errorf("%w: bot.groups 中的 group_id 必须为正数", ErrInvalidConfig)
+		}
+		if seen[g.GroupID] {
+			return fmt.Errorf("%w: bot.groups 中 group_id %d 重复", ErrInvalidConfig, g.GroupID)
+		}
+		seen[g.GroupID] = true
+		if g.Cooldown < 0 {
+			return fmt.Errorf("%w: 群 %d 的 cooldown 不能为负数", ErrInvalidConfig, g.GroupID)
+		}
+	}
+	return nil
+}
+
+// CommandPrefix 生效的命令前缀
+func (b *BotConfig) CommandPrefix() string {
+	if b.Prefix == "" {
+		return "/"
+	}
+	return b.Prefix
+}
+
+// Group 查找群配置
+func (b *BotConfig) Group(id int64) (BotGroup, bool) {
+	for _, g := range b.Groups {
+		if g.GroupID == id {
+			return g, true
+		}
+	}
+	return BotGroup{}, false
+}
+
+// IsAdmin 是否为全局管理员
+func (b *BotConfig) IsAdmin(userID int64) bool {
+	return slices.Contains(b.Admins, userID)
+}
+
+// GroupCooldown 群内生效的冷却时间
+func (b *BotConfig) GroupCooldown(g BotGroup) time.Duration {
+	switch {
+	case g.Cooldown > 0:
+		return g.Cooldown.D()
+	case b.Cooldown > 0:
+		return b.Cooldown.D()
+	default:
+		return DefaultBotCooldown
+	}
+}
+
+// Bot 获取机器人配置副本，未配置时返回 nil
+func (s *AppState) Bot() *BotConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config.Bot == nil {
+		return nil
+	}
+	b := *s.config.Bot
+	return &b
+}
@@ -1,50 +1,77 @@
 package config
 
 import (
-	"log"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bytedance/sonic"
-)
+	"github.com/goccy/go-yaml"
 
-const (
-	ConfigFile    = "config.json"
-	OutputFile    = "response.json"
-	ServerPort    = ":8080"
-	SignInterval  = 24 * time.Hour
-	FetchInterval = 12 * time.Hour
+	"llmaget/clock"
+	"llmaget/store"
 )
 
-// FF14 API 相关常量
-const (
-	Scheme            = "https"
-	BaseURL           = "apiff14risingstones.web.sdo.com"
-	UserInfoPath      = "/api/home/userInfo/getUserInfo"
-	SignRewardsPath   = "/api/home/sign/signRewardList"
-	GetSignRewardPath = "/api/home/sign/getSignReward" // POST
-	BindInfoPath      = "/api/home/groupAndRole/getCharacterBindInfo"
-	SignInPath        = "/api/home/sign/signIn" // POST
-	SearchUserPath    = "/api/common/search"
-)
+// ErrInvalidConfig 配置校验失败
+var ErrInvalidConfig = errors.New("配置无效")
+
+// strictJSON 拒绝未知字段，避免拼写错误的配置项被静默忽略
+var strictJSON = sonic.Config{DisallowUnknownFields: true}.Froze()
+
+// EnvConfigFile 指定配置文件路径的环境变量
+const EnvConfigFile = EnvPrefix + "CONFIG"
+
+// ConfigFile 配置文件路径，可通过命令行 --config 或 LLMAGET_CONFIG 指定，
+// 扩展名为 .yaml/.yml 时按 YAML 读写，否则按 JSON 读写
+var ConfigFile = defaultConfigFile()
+
+// DefaultAccount 默认账号名，当前仅支持单账号
+const DefaultAccount = "default"
+
+func defaultConfigFile() string {
+	if p := os.Getenv(EnvConfigFile); p != "" {
+		return p
+	}
+	return "config.json"
+}
+
+// DefaultUserAgent 默认 User-Agent
+const DefaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
 
 // Config 存储配置信息
 type Config struct {
-	UserAgent string `json:"user_agent"`
-	Cookie    string `json:"cookie"`
+	UserAgent string        `json:"user_agent"`
+	Cookie    string        `json:"cookie"`
+	Settings  *Settings     `json:"settings,omitempty"`
+	Bot       *BotConfig    `json:"bot,omitempty"`
+	Notify    *NotifyConfig `json:"notify,omitempty"`
+	// Sites 按站点名配置的适配器参数
+	Sites map[string]SiteConfig `json:"sites,omitempty"`
 }
 
 // AppState 应用状态
 type AppState struct {
 	mu           sync.RWMutex
 	config       Config
+	settings     Settings
+	sources      map[string]string
+	flags        Settings
 	responseData []byte
 	lastFetchAt  time.Time
+
+	// fileMu 串行化配置文件写入并保护 fileSum
+	fileMu  sync.Mutex
+	fileSum [sha256.Size]byte
 }
 
 var (
-	state = &AppState{}
+	state = &AppState{settings: DefaultSettings()}
 )
 
 // GetState 获取应用状态单例
@@ -52,41 +79,282 @@ func GetState() *AppState {
 	return state
 }
 
+// Current 获取当前生效的运行参数
+func Current() Settings {
+	return state.Settings()
+}
+
+// defaultConfig 默认配置
+func defaultConfig() Config {
+	return Config{
+		UserAgent: DefaultUserAgent,
+		Cookie:    "",
+	}
+}
+
 // Load 从文件加载配置
-func (s *AppState) Load() {
+//
+// 文件损坏时自动从最近的可用备份恢复；文件与备份均不存在时写入默认配置；
+// 均无法解析或校验失败时保留默认配置但不覆盖文件，并返回错误，修正后的文件会被 Watch 重新加载。
+func (s *AppState) Load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	data, err := os.ReadFile(ConfigFile)
+	s.config = defaultConfig()
+
+	data, err := store.ReadFile(ConfigFile, s.validateFile, store.DefaultBackups)
+	if errors.Is(err, store.ErrCorrupt) {
+		data, err = os.ReadFile(ConfigFile)
+	}
 	if err != nil {
-		log.Printf("⚠️ 配置文件不存在，使用默认配置")
-		s.config = Config{
-			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			Cookie:    "",
+		slog.Warn("⚠️ 配置文件不存在，使用默认配置", "file", ConfigFile)
+		if err := s.applySettingsLocked(s.config); err != nil {
+			return err
 		}
-		s.saveUnsafe()
-		return
+		return s.saveUnsafe()
 	}
 
-	if err := sonic.Unmarshal(data, &s.config); err != nil {
-		log.Printf("⚠️ 配置文件解析失败: %v，使用默认配置", err)
-		s.config = Config{
-			UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
-			Cookie:    "",
+	cfg, err := ParseConfig(data)
+	if err == nil {
+		err = s.applySettingsLocked(cfg)
+	}
+	if err != nil {
+		s.setFileSum(data)
+		if fallbackErr := s.applySettingsLocked(s.config); fallbackErr != nil {
+			s.settings = DefaultSettings()
 		}
-		return
+		return fmt.Errorf("配置文件 %s 无效: %w", ConfigFile, err)
+	}
+
+	s.config = cfg
+	s.setFileSum(data)
+	slog.Info("✅ 配置加载成功", "file", ConfigFile, "data_dir", s.settings.DataDir)
+	return nil
+}
+
+// validateFile 校验配置文件内容能否解析并得到有效的运行参数
+func (s *AppState) validateFile(data []byte) error {
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return err
+	}
+	_, _, err = s.resolveSettings(cfg)
+	return err
+}
+
+// ValidateFile 校验配置文件内容，供从备份恢复时使用
+func ValidateFile(data []byte) error {
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	return state.validateFile(data)
+}
+
+// SetFlagSettings 设置命令行层的配置覆盖，需在 Load 之前调用
+func (s *AppState) SetFlagSettings(flags Settings) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = flags
+}
+
+// resolveSettings 按 默认值 → 文件 → 环境变量 → 命令行 计算生效的运行参数
+func (s *AppState) resolveSettings(cfg Config) (Settings, map[string]string, error) {
+	eff := DefaultSettings()
+	sources := make(map[string]string)
+	for _, key := range SettingKeys() {
+		sources[key] = SourceDefault
+	}
+
+	if cfg.Settings != nil {
+		overlay(&eff, *cfg.Settings, SourceFile, sources)
+	}
+	env, err := envSettings()
+	if err != nil {
+		return Settings{}, nil, fmt.Errorf("环境变量: %w", err)
+	}
+	overlay(&eff, env, SourceEnv, sources)
+	overlay(&eff, s.flags, SourceFlag, sources)
+
+	// 相对路径的数据目录以配置文件所在目录为基准，与启动时的工作目录无关
+	base := filepath.Dir(ConfigFile)
+	if eff.DataDir == "" {
+		eff.DataDir = base
+	} else if !filepath.IsAbs(eff.DataDir) {
+		eff.DataDir = filepath.Join(base, eff.DataDir)
+	}
+
+	if err := eff.Validate(); err != nil {
+		return Settings{}, nil, err
+	}
+	return eff, sources, nil
+}
+
+// applySettingsLocked 计算并应用运行参数（调用方需持有写锁）
+func (s *AppState) applySettingsLocked(cfg Config) error {
+	eff, sources, err := s.resolveSettings(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(eff.DataDir, 0755); err != nil {
+		return fmt.Errorf("创建数据目录失败: %w", err)
+	}
+	loc, err := clock.LoadLocation(eff.Timezone)
+	if err != nil {
+		return err
+	}
+	clock.SetLocation(loc)
+	s.settings = eff
+	s.sources = sources
+	return nil
+}
+
+// Settings 获取生效的运行参数
+func (s *AppState) Settings() Settings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings
+}
+
+// EffectiveConfig 生效配置视图，敏感字段已脱敏
+type EffectiveConfig struct {
+	ConfigFile string            `json:"config_file"`
+	UserAgent  string            `json:"user_agent"`
+	Cookie     string            `json:"cookie"`
+	Settings   Settings          `json:"settings"`
+	Sources    map[string]string `json:"sources"`
+}
+
+// Effective 获取生效配置视图
+func (s *AppState) Effective() EffectiveConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sources := make(map[string]string, len(s.sources))
+	for k, v := range s.sources {
+		sources[k] = v
+	}
+	return EffectiveConfig{
+		ConfigFile: ConfigFile,
+		UserAgent:  s.config.UserAgent,
+		Cookie:     redact(s.config.Cookie),
+		Settings:   s.settings.Redacted(),
+		Sources:    sources,
+	}
+}
+
+// isYAML 配置文件是否为 YAML 格式
+func isYAML() bool {
+	ext := strings.ToLower(filepath.Ext(ConfigFile))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// encodeConfig 按配置文件格式编码
+func encodeConfig(cfg Config) ([]byte, error) {
+	if isYAML() {
+		return yaml.Marshal(cfg)
+	}
+	return sonic.MarshalIndent(cfg, "", "  ")
+}
+
+// ParseConfig 解析并校验配置文件内容
+func ParseConfig(data []byte) (Config, error) {
+	cfg := defaultConfig()
+	var err error
+	if isYAML() {
+		err = yaml.UnmarshalWithOptions(data, &cfg, yaml.Strict(), yaml.DisallowUnknownField())
+	} else {
+		err = strictJSON.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("解析失败: %w", err)
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// ExportFile 读取并校验当前配置文件，统一转换为 JSON，便于在 JSON 与 YAML 配置之间迁移
+func ExportFile() ([]byte, error) {
+	data, err := os.ReadFile(ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("配置文件 %s 无效: %w", ConfigFile, err)
+	}
+	return sonic.MarshalIndent(cfg, "", "  ")
+}
+
+// ImportFile 校验 ExportFile 导出的 JSON 配置，按当前配置文件格式编码，并返回其生效的运行参数
+func ImportFile(data []byte) ([]byte, Settings, error) {
+	cfg := defaultConfig()
+	if err := strictJSON.Unmarshal(data, &cfg); err != nil {
+		return nil, Settings{}, fmt.Errorf("解析失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, Settings{}, err
+	}
+	out, err := encodeConfig(cfg)
+	if err != nil {
+		return nil, Settings{}, err
 	}
 
-	log.Printf("✅ 配置加载成功")
+	state.mu.RLock()
+	defer state.mu.RUnlock()
+	eff, _, err := state.resolveSettings(cfg)
+	if err != nil {
+		return nil, Settings{}, err
+	}
+	return out, eff, nil
+}
+
+// Validate 校验配置内容
+func (c Config) Validate() error {
+	if strings.ContainsAny(c.UserAgent, "\r\n") {
+		return fmt.Errorf("%w: user_agent 不能包含换行", ErrInvalidConfig)
+	}
+	if strings.HasPrefix(c.Cookie, "ff14risingstones=") {
+		return fmt.Errorf("%w: cookie 只需填写 ff14risingstones 的值，不要包含名称", ErrInvalidConfig)
+	}
+	if strings.ContainsAny(c.Cookie, "; \t\r\n") {
+		return fmt.Errorf("%w: cookie 不能包含分号或空白字符", ErrInvalidConfig)
+	}
+	if c.Bot != nil {
+		if err := c.Bot.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.Notify != nil {
+		return c.Notify.Validate()
+	}
+	return nil
 }
 
 // saveUnsafe 保存配置（不加锁，内部使用）
 func (s *AppState) saveUnsafe() error {
-	data, err := sonic.MarshalIndent(s.config, "", "  ")
+	data, err := encodeConfig(s.config)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(ConfigFile, data, 0644)
+
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	if err := store.WriteFile(ConfigFile, data, 0644, store.DefaultBackups); err != nil {
+		return err
+	}
+	s.fileSum = sha256.Sum256(data)
+	return nil
+}
+
+// setFileSum 记录当前配置文件内容的摘要
+func (s *AppState) setFileSum(data []byte) {
+	s.fileMu.Lock()
+	defer s.fileMu.Unlock()
+	s.fileSum = sha256.Sum256(data)
 }
 
 // Save 保存配置到文件
@@ -96,6 +364,20 @@ func (s *AppState) Save() error {
 	return s.saveUnsafe()
 }
 
+// Flush 将配置与最新响应数据写入磁盘，用于退出前落盘
+func (s *AppState) Flush() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.saveUnsafe(); err != nil {
+		return err
+	}
+	if len(s.responseData) == 0 {
+		return nil
+	}
+	return store.WriteFile(s.settings.OutputFile(), s.responseData, 0644, store.DefaultBackups)
+}
+
 // GetConfig 获取配置副本
 func (s *AppState) GetConfig() Config {
 	s.mu.RLock()
@@ -106,12 +388,18 @@ func (s *AppState) GetConfig() Config {
 // SetConfig 更新配置
 func (s *AppState) SetConfig(cfg Config) error {
 	s.mu.Lock()
+	next := s.config
 	if cfg.UserAgent != "" {
-		s.config.UserAgent = cfg.UserAgent
+		next.UserAgent = cfg.UserAgent
 	}
 	if cfg.Cookie != "" {
-		s.config.Cookie = cfg.Cookie
+		next.Cookie = cfg.Cookie
+	}
+	if err := next.Validate(); err != nil {
+		s.mu.Unlock()
+		return err
 	}
+	s.config = next
 	s.mu.Unlock()
 	return s.Save()
 }
@@ -135,7 +423,7 @@ func (s *AppState) SetResponseData(data []byte) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.responseData = data
-	s.lastFetchAt = time.Now()
+	s.lastFetchAt = clock.Now()
 }
 
 // GetLastFetchAt 获取最后获取时间
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// 通知渠道类型
+const (
+	NotifyWebhook    = "webhook"    // POST JSON {"event","title","text"}
+	NotifyWeCom      = "wecom"      // 企业微信群机器人
+	NotifyBark       = "bark"       // Bark，url 为 https://api.day.app/<key>
+	NotifyServerChan = "serverchan" // Server 酱，url 为 https://sctapi.ftqq.com/<sendkey>.send
+)
+
+// NotifyConfig 通知配置，未配置时不发送通知
+type NotifyConfig struct {
+	Channels []NotifyChannel `json:"channels,omitempty"`
+}
+
+// NotifyChannel 单个通知渠道，密钥包含在 url 中
+type NotifyChannel struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	URL  string `json:"url"`
+	// Events 订阅的事件，为空时接收全部事件
+	Events []string `json:"events,omitempty"`
+}
+
+// Wants 渠道是否订阅了该事件
+func (c NotifyChannel) Wants(event string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, event)
+}
+
+// Validate 校验通知配置
+func (n *NotifyConfig) Validate() error {
+	seen := make(map[string]bool, len(n.Channels))
+	for _, ch := range n.Channels {
+		if ch.Name == "" {
+			return fmt.Errorf("%w: notify.channels 中的 name 不能为空", ErrInvalidConfig)
+		}
+		if seen[ch.Name] {
+			return fmt.Errorf("%w: notify.channels 中 %s 重复", ErrInvalidConfig, ch.Name)
+		}
+		seen[ch.Name] = true
+		switch ch.Type {
+		case NotifyWebhook, NotifyWeCom, NotifyBark, NotifyServerChan:
+		default:
+			return fmt.Errorf("%w: 通知渠道 %s 的 type 只能为 webhook/wecom/bark/serverchan", ErrInvalidConfig, ch.Name)
+		}
+		u, err := url.Parse(ch.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: 通知渠道 %s 的 url 需为 http(s) 地址", ErrInvalidConfig, ch.Name)
+		}
+	}
+	return nil
+}
+
+// Notify 获取通知配置副本，未配置时返回 nil
+func (s *AppState) Notify() *NotifyConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.config.Notify == nil {
+		return nil
+	}
+	n := *s.config.Notify
+	return &n
+}
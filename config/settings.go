@@ -0,0 +1,536 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"llmaget/clock"
+)
+
+// EnvPrefix 环境变量前缀，如 LLMAGET_SERVER_PORT 覆盖 server_port
+const EnvPrefix = "LLMAGET_"
+
+// 配置来源
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// 数据目录下的文件名
+const (
+	OutputFileName    = "response.json"
+	JobsFileName      = "jobs.json"
+	RewardsFileName   = "rewards.json"
+	SnapshotsFileName = "snapshots.jsonl"
+	ShopFileName      = "shop.jsonl"
+	LedgerFileName    = "ledger.jsonl"
+)
+
+// Duration 支持 "12h" 形式读写的时长
+type Duration time.Duration
+
+// D 转换为 time.Duration
+func (d Duration) D() time.Duration {
+	return time.Duration(d)
+}
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalText 实现 encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Settings 运行参数
+//
+// 生效值按 默认值 → 配置文件 settings 段 → LLMAGET_* 环境变量 → 命令行 --set 的顺序逐层覆盖，
+// 零值表示该层未设置。
+type Settings struct {
+	ServerPort          string   `json:"server_port,omitempty"`
+	DataDir             string   `json:"data_dir,omitempty"`
+	SignInterval        Duration `json:"sign_interval,omitempty"`
+	FetchInterval       Duration `json:"fetch_interval,omitempty"`
+	ShutdownGrace       Duration `json:"shutdown_grace,omitempty"`
+	ConfigWatchInterval Duration `json:"config_watch_interval,omitempty"`
+	LogFormat           string   `json:"log_format,omitempty"`
+	LogLevel            string   `json:"log_level,omitempty"`
+	Timezone            string   `json:"timezone,omitempty"`
+
+	// FF14 API
+	Scheme            string `json:"scheme,omitempty"`
+	BaseURL           string `json:"base_url,omitempty"`
+	UserInfoPath      string `json:"user_info_path,omitempty"`
+	SignRewardsPath   string `json:"sign_rewards_path,omitempty"`
+	GetSignRewardPath string `json:"get_sign_reward_path,omitempty"`
+	BindInfoPath      string `json:"bind_info_path,omitempty"`
+	SignInPath        string `json:"sign_in_path,omitempty"`
+	SearchUserPath    string `json:"search_user_path,omitempty"`
+	MissionListPath   string `json:"mission_list_path,omitempty"`
+	MissionClaimPath  string `json:"mission_claim_path,omitempty"`
+	PostsListPath     string `json:"posts_list_path,omitempty"`
+	PostDetailPath    string `json:"post_detail_path,omitempty"`
+	PostLikePath      string `json:"post_like_path,omitempty"`
+	PostCommentPath   string `json:"post_comment_path,omitempty"`
+	PointsPath        string `json:"points_path,omitempty"`
+	ShopItemsPath     string `json:"shop_items_path,omitempty"`
+
+	// 社区任务：mission_actions 为自动完成的动作（browse、like、comment，逗号分隔），
+	// 为空时只领取已完成任务的积分；comment 需同时设置 mission_comment 作为评论内容
+	MissionActions string `json:"mission_actions,omitempty"`
+	MissionComment string `json:"mission_comment,omitempty"`
+
+	// 积分商城：每隔 shop_interval 记录积分余额与商品库存，shop_watch 为关注的商品（名称或 ID，逗号分隔），
+	// 关注的商品积分足够兑换或补货时发送 shop 通知
+	ShopInterval Duration `json:"shop_interval,omitempty"`
+	ShopWatch    string   `json:"shop_watch,omitempty"`
+
+	// 上游限速：每个主机与每个账号各一个令牌桶，批量操作在请求间插入随机间隔
+	UpstreamQPS     float64  `json:"upstream_qps,omitempty"`
+	AccountQPS      float64  `json:"account_qps,omitempty"`
+	BulkDelayMin    Duration `json:"bulk_delay_min,omitempty"`
+	BulkDelayMax    Duration `json:"bulk_delay_max,omitempty"`
+	ThrottleCodes   string   `json:"throttle_codes,omitempty"`
+	ThrottleBackoff Duration `json:"throttle_backoff,omitempty"`
+
+	// 熔断：连续失败 breaker_threshold 次后熔断，breaker_cooldown 后放行一次探测请求
+	BreakerThreshold int      `json:"breaker_threshold,omitempty"`
+	BreakerCooldown  Duration `json:"breaker_cooldown,omitempty"`
+
+	// 查询缓存：cache_file 为空时仅缓存在内存中，否则持久化到数据目录下的该文件
+	SearchCacheTTL  Duration `json:"search_cache_ttl,omitempty"`
+	ProfileCacheTTL Duration `json:"profile_cache_ttl,omitempty"`
+	CacheFile       string   `json:"cache_file,omitempty"`
+
+	// 书签推送 Cookie：/llmaget/set 生成的一次性令牌有效期为 cookie_push_ttl，
+	// 只接受来自 cookie_push_origins（逗号分隔的 Origin）的请求
+	CookiePushOrigins string   `json:"cookie_push_origins,omitempty"`
+	CookiePushTTL     Duration `json:"cookie_push_ttl,omitempty"`
+
+	// 管理接口：备份、恢复等接口需携带该令牌，为空时管理接口不可用
+	AdminToken string `json:"admin_token,omitempty"`
+
+	// 函数调用接口：代理可调用的工具名，逗号分隔，* 表示全部；为空时只开放只读工具
+	AgentTools string `json:"agent_tools,omitempty"`
+
+	// 周报：配置 llm_base_url 与 llm_model 后由 OpenAI 兼容接口撰写，否则使用固定模板
+	LLMBaseURL string   `json:"llm_base_url,omitempty"`
+	LLMModel   string   `json:"llm_model,omitempty"`
+	LLMAPIKey  string   `json:"llm_api_key,omitempty"`
+	LLMTimeout Duration `json:"llm_timeout,omitempty"`
+
+	// 工作流：该目录下的 *.yaml 为声明式任务定义，相对路径基于 data_dir
+	WorkflowDir string `json:"workflow_dir,omitempty"`
+}
+
+// secretSettings 敏感配置项，展示与日志中需脱敏
+var secretSettings = map[string]bool{"admin_token": true, "llm_api_key": true}
+
+// DefaultSettings 默认运行参数
+func DefaultSettings() Settings {
+	return Settings{
+		ServerPort:          ":8080",
+		SignInterval:        Duration(24 * time.Hour),
+		FetchInterval:       Duration(12 * time.Hour),
+		ShutdownGrace:       Duration(20 * time.Second),
+		ConfigWatchInterval: Duration(2 * time.Second),
+		LogFormat:           "text",
+		LogLevel:            "info",
+		Timezone:            clock.DefaultTimezone,
+
+		Scheme:            "https",
+		BaseURL:           "apiff14risingstones.web.sdo.com",
+		UserInfoPath:      "/api/home/userInfo/getUserInfo",
+		SignRewardsPath:   "/api/home/sign/signRewardList",
+		GetSignRewardPath: "/api/home/sign/getSignReward", // POST
+		BindInfoPath:      "/api/home/groupAndRole/getCharacterBindInfo",
+		SignInPath:        "/api/home/sign/signIn", // POST
+		SearchUserPath:    "/api/common/search",
+		MissionListPath:   "/api/home/userTask/taskList",
+		MissionClaimPath:  "/api/home/userTask/getTaskReward", // POST
+		PostsListPath:     "/api/home/posts/postsList",
+		PostDetailPath:    "/api/home/posts/postsDetail",
+		PostLikePath:      "/api/home/posts/like",    // POST
+		PostCommentPath:   "/api/home/posts/comment", // POST
+		PointsPath:        "/api/home/mall/getUserIntegral",
+		ShopItemsPath:     "/api/home/mall/goodsList",
+
+		UpstreamQPS:     2,
+		AccountQPS:      1,
+		BulkDelayMin:    Duration(500 * time.Millisecond),
+		BulkDelayMax:    Duration(1500 * time.Millisecond),
+		ThrottleBackoff: Duration(30 * time.Second),
+
+		BreakerThreshold: 5,
+		BreakerCooldown:  Duration(30 * time.Second),
+
+		SearchCacheTTL:  Duration(24 * time.Hour),
+		ProfileCacheTTL: Duration(time.Hour),
+
+		ShopInterval: Duration(6 * time.Hour),
+
+		CookiePushOrigins: "https://ff14risingstones.web.sdo.com",
+		CookiePushTTL:     Duration(10 * time.Minute),
+
+		LLMTimeout: Duration(2 * time.Minute),
+
+		WorkflowDir: "workflows",
+	}
+}
+
+// Validate 校验运行参数
+func (s Settings) Validate() error {
+	if s.ServerPort == "" || !strings.Contains(s.ServerPort, ":") {
+		return fmt.Errorf("%w: server_port 需为 host:port 或 :port 形式", ErrInvalidConfig)
+	}
+	for name, d := range map[string]Duration{
+		"sign_interval":         s.SignInterval,
+		"fetch_interval":        s.FetchInterval,
+		"shutdown_grace":        s.ShutdownGrace,
+		"config_watch_interval": s.ConfigWatchInterval,
+		"throttle_backoff":      s.ThrottleBackoff,
+		"breaker_cooldown":      s.BreakerCooldown,
+		"search_cache_ttl":      s.SearchCacheTTL,
+		"profile_cache_ttl":     s.ProfileCacheTTL,
+		"llm_timeout":           s.LLMTimeout,
+		"shop_interval":         s.ShopInterval,
+		"cookie_push_ttl":       s.CookiePushTTL,
+	} {
+		if d <= 0 {
+			return fmt.Errorf("%w: %s 必须大于 0", ErrInvalidConfig, name)
+		}
+	}
+	switch s.LogFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("%w: log_format 只能为 text 或 json", ErrInvalidConfig)
+	}
+	switch s.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		return fmt.Errorf("%w: log_level 只能为 debug/info/warn/error", ErrInvalidConfig)
+	}
+	if _, err := clock.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("%w: timezone %v", ErrInvalidConfig, err)
+	}
+	if s.Scheme != "http" && s.Scheme != "https" {
+		return fmt.Errorf("%w: scheme 只能为 http 或 https", ErrInvalidConfig)
+	}
+	if s.BaseURL == "" || strings.Contains(s.BaseURL, "/") {
+		return fmt.Errorf("%w: base_url 需为主机名，不含协议和路径", ErrInvalidConfig)
+	}
+	if s.UpstreamQPS <= 0 || s.AccountQPS <= 0 {
+		return fmt.Errorf("%w: upstream_qps 与 account_qps 必须大于 0", ErrInvalidConfig)
+	}
+	if s.BulkDelayMin < 0 || s.BulkDelayMax < s.BulkDelayMin {
+		return fmt.Errorf("%w: bulk_delay_max 不能小于 bulk_delay_min", ErrInvalidConfig)
+	}
+	if s.BreakerThreshold <= 0 {
+		return fmt.Errorf("%w: breaker_threshold 必须大于 0", ErrInvalidConfig)
+	}
+	if strings.ContainsAny(s.CacheFile, `/\`) {
+		return fmt.Errorf("%w: cache_file 只需填写文件名，保存在 data_dir 下", ErrInvalidConfig)
+	}
+	if _, err := s.ThrottleCodeSet(); err != nil {
+		return err
+	}
+	if s.LLMBaseURL != "" {
+		u, err := url.Parse(s.LLMBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%w: llm_base_url 需为 http(s) 地址，如 http://127.0.0.1:11434/v1", ErrInvalidConfig)
+		}
+		if s.LLMModel == "" {
+			return fmt.Errorf("%w: 配置 llm_base_url 时 llm_model 不能为空", ErrInvalidConfig)
+		}
+	}
+	actions, err := s.MissionActionSet()
+	if err != nil {
+		return err
+	}
+	if actions[MissionComment] && strings.TrimSpace(s.MissionComment) == "" {
+		return fmt.Errorf("%w: mission_actions 包含 comment 时 mission_comment 不能为空", ErrInvalidConfig)
+	}
+	for _, name := range s.AgentToolList() {
+		if strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("%w: agent_tools 需为逗号分隔的工具名: %s", ErrInvalidConfig, name)
+		}
+	}
+	for _, origin := range s.CookiePushOriginList() {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || origin != u.Scheme+"://"+u.Host {
+			return fmt.Errorf("%w: cookie_push_origins 需为逗号分隔的 Origin，如 https://ff14risingstones.web.sdo.com: %s", ErrInvalidConfig, origin)
+		}
+	}
+	for name, p := range map[string]string{
+		"user_info_path":       s.UserInfoPath,
+		"sign_rewards_path":    s.SignRewardsPath,
+		"get_sign_reward_path": s.GetSignRewardPath,
+		"bind_info_path":       s.BindInfoPath,
+		"sign_in_path":         s.SignInPath,
+		"search_user_path":     s.SearchUserPath,
+		"mission_list_path":    s.MissionListPath,
+		"mission_claim_path":   s.MissionClaimPath,
+		"posts_list_path":      s.PostsListPath,
+		"post_detail_path":     s.PostDetailPath,
+		"post_like_path":       s.PostLikePath,
+		"post_comment_path":    s.PostCommentPath,
+		"points_path":          s.PointsPath,
+		"shop_items_path":      s.ShopItemsPath,
+	} {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf("%w: %s 需以 / 开头", ErrInvalidConfig, name)
+		}
+	}
+	return nil
+}
+
+// ThrottleCodeSet 解析 throttle_codes，返回表示上游限流的业务码集合
+func (s Settings) ThrottleCodeSet() (map[int]bool, error) {
+	codes := make(map[int]bool)
+	for _, part := range strings.Split(s.ThrottleCodes, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("%w: throttle_codes 需为逗号分隔的业务码: %s", ErrInvalidConfig, part)
+		}
+		codes[code] = true
+	}
+	return codes, nil
+}
+
+// 社区任务可自动完成的动作
+const (
+	MissionBrowse  = "browse"
+	MissionLike    = "like"
+	MissionComment = "comment"
+)
+
+// MissionActionSet 解析 mission_actions，返回允许自动完成的动作集合
+func (s Settings) MissionActionSet() (map[string]bool, error) {
+	actions := make(map[string]bool)
+	for _, part := range strings.Split(s.MissionActions, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+		case MissionBrowse, MissionLike, MissionComment:
+			actions[part] = true
+		default:
+			return nil, fmt.Errorf("%w: mission_actions 只能包含 browse、like、comment: %s", ErrInvalidConfig, part)
+		}
+	}
+	return actions, nil
+}
+
+// ShopWatchList 解析 shop_watch，返回关注的商品名称或 ID
+func (s Settings) ShopWatchList() []string {
+	var items []string
+	for _, part := range strings.Split(s.ShopWatch, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// CookiePushOriginList 解析 cookie_push_origins，返回允许推送 Cookie 的 Origin
+func (s Settings) CookiePushOriginList() []string {
+	var origins []string
+	for _, part := range strings.Split(s.CookiePushOrigins, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			origins = append(origins, part)
+		}
+	}
+	return origins
+}
+
+// AgentToolList 解析 agent_tools，返回允许代理调用的工具名
+func (s Settings) AgentToolList() []string {
+	var names []string
+	for _, part := range strings.Split(s.AgentTools, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// Redacted 返回敏感配置项已脱敏的副本，用于展示
+func (s Settings) Redacted() Settings {
+	if s.AdminToken != "" {
+		s.AdminToken = redact(s.AdminToken)
+	}
+	if s.LLMAPIKey != "" {
+		s.LLMAPIKey = redact(s.LLMAPIKey)
+	}
+	return s
+}
+
+// DataPath 返回数据目录下的文件路径
+func (s Settings) DataPath(name string) string {
+	return filepath.Join(s.DataDir, name)
+}
+
+// OutputFile 基础信息响应保存路径
+func (s Settings) OutputFile() string {
+	return s.DataPath(OutputFileName)
+}
+
+// JobsFile 任务历史保存路径
+func (s Settings) JobsFile() string {
+	return s.DataPath(JobsFileName)
+}
+
+// RewardsFile 每月签到奖励历史保存路径
+func (s Settings) RewardsFile() string {
+	return s.DataPath(RewardsFileName)
+}
+
+// SnapshotsFile 角色信息快照保存路径
+func (s Settings) SnapshotsFile() string {
+	return s.DataPath(SnapshotsFileName)
+}
+
+// ShopFile 积分与商城库存记录保存路径
+func (s Settings) ShopFile() string {
+	return s.DataPath(ShopFileName)
+}
+
+// LedgerFile 签到流水保存路径
+func (s Settings) LedgerFile() string {
+	return s.DataPath(LedgerFileName)
+}
+
+// WorkflowPath 工作流定义目录
+func (s Settings) WorkflowPath() string {
+	if filepath.IsAbs(s.WorkflowDir) {
+		return s.WorkflowDir
+	}
+	return s.DataPath(s.WorkflowDir)
+}
+
+// SettingKeys 返回所有配置项名称
+func SettingKeys() []string {
+	t := reflect.TypeOf(Settings{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, settingKey(t.Field(i)))
+	}
+	return keys
+}
+
+// ParseSettings 将 key=value 形式的配置项解析为 Settings
+func ParseSettings(values map[string]string) (Settings, error) {
+	var out Settings
+	v := reflect.ValueOf(&out).Elem()
+	t := v.Type()
+
+	for key, raw := range values {
+		idx := -1
+		for i := 0; i < t.NumField(); i++ {
+			if settingKey(t.Field(i)) == key {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return Settings{}, fmt.Errorf("%w: 未知配置项 %s", ErrInvalidConfig, key)
+		}
+
+		field := v.Field(idx)
+		switch field.Interface().(type) {
+		case Duration:
+			d, err := time.ParseDuration(raw)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%w: %s 时长格式错误: %v", ErrInvalidConfig, key, err)
+			}
+			field.SetInt(int64(d))
+		case float64:
+			f, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%w: %s 需为数字: %v", ErrInvalidConfig, key, err)
+			}
+			field.SetFloat(f)
+		case int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return Settings{}, fmt.Errorf("%w: %s 需为整数: %v", ErrInvalidConfig, key, err)
+			}
+			field.SetInt(int64(n))
+		default:
+			field.SetString(raw)
+		}
+	}
+	return out, nil
+}
+
+// envSettings 读取 LLMAGET_* 环境变量中的配置项
+func envSettings() (Settings, error) {
+	values := make(map[string]string)
+	for _, key := range SettingKeys() {
+		if raw, ok := os.LookupEnv(EnvPrefix + strings.ToUpper(key)); ok && raw != "" {
+			values[key] = raw
+		}
+	}
+	return ParseSettings(values)
+}
+
+// overlay 用 over 中的非零字段覆盖 base，并记录来源
+func overlay(base *Settings, over Settings, source string, sources map[string]string) {
+	bv := reflect.ValueOf(base).Elem()
+	ov := reflect.ValueOf(over)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if ov.Field(i).IsZero() {
+			continue
+		}
+		bv.Field(i).Set(ov.Field(i))
+		sources[settingKey(t.Field(i))] = source
+	}
+}
+
+// diffSettings 列出两份运行参数的差异
+func diffSettings(old, cur Settings) []string {
+	var changes []string
+	ov := reflect.ValueOf(old)
+	cv := reflect.ValueOf(cur)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if ov.Field(i).Interface() == cv.Field(i).Interface() {
+			continue
+		}
+		key := settingKey(t.Field(i))
+		if secretSettings[key] {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", key, redact(ov.Field(i).String()), redact(cv.Field(i).String())))
+			continue
+		}
+		changes = append(changes, fmt.Sprintf("%s: %v -> %v", key, ov.Field(i).Interface(), cv.Field(i).Interface()))
+	}
+	return changes
+}
+
+func settingKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	return name
+}
@@ -0,0 +1,18 @@
+package config
+
+// SiteConfig 站点适配器配置，石之家的 Cookie 仍使用顶层 cookie 字段
+type SiteConfig struct {
+	// Disabled 停用后不再定时执行该站点的任务
+	Disabled bool `json:"disabled,omitempty"`
+	// Cookie 站点的登录凭据
+	Cookie string `json:"cookie,omitempty"`
+	// Options 适配器自定义参数
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// Site 获取站点配置，未配置时返回零值
+func (s *AppState) Site(name string) SiteConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.config.Sites[name]
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"log/slog"
+	"os"
+	"reflect"
+	"time"
+)
+
+// Watch 定期检查配置文件，内容变化时校验并热加载，直到 ctx 结束
+//
+// 采用轮询而非 inotify，这样编辑器的"写临时文件再重命名"式保存也能被识别。
+// 校验失败的文件会被拒绝，当前配置保持不变。
+func (s *AppState) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slog.Info("👀 配置文件监听启动", "file", ConfigFile, "interval", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.reloadIfChanged()
+		}
+	}
+}
+
+// reloadIfChanged 配置文件内容变化时重新加载
+func (s *AppState) reloadIfChanged() {
+	data, err := os.ReadFile(ConfigFile)
+	if err != nil {
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	s.fileMu.Lock()
+	unchanged := sum == s.fileSum
+	s.fileSum = sum
+	s.fileMu.Unlock()
+	if unchanged {
+		return
+	}
+
+	cfg, err := ParseConfig(data)
+	if err != nil {
+		slog.Error("❌ 配置文件变更无效，保留当前配置", "file", ConfigFile, "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	old, oldSettings := s.config, s.settings
+	if err := s.applySettingsLocked(cfg); err != nil {
+		s.mu.Unlock()
+		slog.Error("❌ 配置文件变更无效，保留当前配置", "file", ConfigFile, "error", err)
+		return
+	}
+	s.config = cfg
+	newSettings := s.settings
+	s.mu.Unlock()
+
+	changes := diffConfig(old, cfg)
+	if settingsChanges := diffSettings(oldSettings, newSettings); len(settingsChanges) > 0 {
+		changes = append(changes, settingsChanges...)
+		slog.Warn("⚠️ 运行参数已变更，监听端口、定时间隔等参数需重启后生效")
+	}
+	if len(changes) == 0 {
+		slog.Info("🔄 配置文件已变更，内容无差异", "file", ConfigFile)
+		return
+	}
+	slog.Info("🔄 配置已热加载", "file", ConfigFile, "changes", changes)
+}
+
+// diffConfig 列出配置变化，Cookie 只记录是否变化
+func diffConfig(old, cur Config) []string {
+	var changes []string
+	if old.UserAgent != cur.UserAgent {
+		changes = append(changes, "user_agent: "+old.UserAgent+" -> "+cur.UserAgent)
+	}
+	if old.Cookie != cur.Cookie {
+		changes = append(changes, "cookie: "+redact(old.Cookie)+" -> "+redact(cur.Cookie))
+	}
+	if !reflect.DeepEqual(old.Bot, cur.Bot) {
+		changes = append(changes, "bot: 已变更")
+	}
+	if !reflect.DeepEqual(old.Notify, cur.Notify) {
+		changes = append(changes, "notify: 已变更")
+	}
+	if !reflect.DeepEqual(old.Sites, cur.Sites) {
+		changes = append(changes, "sites: 已变更")
+	}
+	return changes
+}
+
+// redact 脱敏显示敏感值
+func redact(v string) string {
+	if v == "" {
+		return "(空)"
+	}
+	if len(v) <= 8 {
+		return "****"
+	}
+	return v[:4] + "****" + v[len(v)-4:]
+}
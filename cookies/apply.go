@@ -0,0 +1,35 @@
+package cookies
+
+import (
+	"context"
+	"log/slog"
+
+	"llmaget/config"
+	"llmaget/sites"
+	"llmaget/sites/ff14"
+)
+
+// Result 保存 Cookie 并检查会话的结果
+type Result struct {
+	Import
+	Session sites.Session `json:"session"`
+}
+
+// Apply 将提取的 ff14risingstones 写入配置，并立即检查石之家会话
+//
+// 会话无效时 Cookie 仍会保存，由调用方根据 Session 提示用户重新获取。
+func Apply(ctx context.Context, imp *Import, reg *sites.Registry) (*Result, error) {
+	if err := config.GetState().SetConfig(config.Config{Cookie: imp.Value}); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "🍪 Cookie 已更新", "format", imp.Format, "domain", imp.Domain, "expires", imp.Expires)
+
+	res := &Result{Import: *imp}
+	if a, ok := reg.Get(ff14.Name); ok {
+		res.Session = reg.CheckSession(ctx, a)
+	}
+	if !res.Session.Valid {
+		slog.WarnContext(ctx, "⚠️ 新 Cookie 的会话检查未通过", "error", res.Session.Error)
+	}
+	return res, nil
+}
@@ -0,0 +1,312 @@
+// Package cookies 从浏览器导出的 Cookie 中提取石之家的登录凭据
+package cookies
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+)
+
+// SessionCookie 石之家登录凭据的 Cookie 名称，也是唯一需要保存的 Cookie
+const SessionCookie = "ff14risingstones"
+
+// 支持的导入格式
+const (
+	FormatNetscape = "cookies.txt" // Netscape cookies.txt
+	FormatHAR      = "har"         // 浏览器开发者工具导出的 HAR
+	FormatJSON     = "json"        // EditThisCookie / Cookie-Editor 导出的 JSON 数组
+	FormatHeader   = "header"      // Cookie 请求头，可带 "Cookie:" 前缀
+	FormatValue    = "value"       // 只有 ff14risingstones 的值
+)
+
+var (
+	// ErrUnknownFormat 无法识别的导入内容
+	ErrUnknownFormat = errors.New("无法识别的 Cookie 格式")
+	// ErrNotFound 导入内容中没有 ff14risingstones
+	ErrNotFound = errors.New("未找到 " + SessionCookie + " Cookie")
+	// ErrExpired ff14risingstones 已过期
+	ErrExpired = errors.New(SessionCookie + " Cookie 已过期")
+)
+
+// Cookie 导入内容中的一条 Cookie
+type Cookie struct {
+	Name   string
+	Value  string
+	Domain string
+	// Expires 过期时间，会话 Cookie 或格式中没有过期时间时为零值
+	Expires time.Time
+}
+
+// Import 提取结果
+type Import struct {
+	Format string `json:"format"`
+	// Value ff14risingstones 的值，写入配置的 cookie
+	Value  string `json:"-"`
+	Domain string `json:"domain,omitempty"`
+	// Expires 过期时间，会话 Cookie 或无法得知时为 nil
+	Expires *time.Time `json:"expires,omitempty"`
+	// Found 导入内容中的 Cookie 总数，其余 Cookie 不需要保存
+	Found int `json:"found"`
+}
+
+// Extract 识别导入内容的格式并提取 ff14risingstones
+//
+// 存在多条时优先选择域名与 host（石之家接口域名）匹配的一条，同等条件下取最后出现的；
+// 过期时间取同值 Cookie 中最晚的一个，HAR 中请求 Cookie 没有过期时间，由响应的 Set-Cookie 补全。
+func Extract(data []byte, host string) (*Import, error) {
+	format, list, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	host = hostname(host)
+	var best *Cookie
+	bestMatch := false
+	for i := range list {
+		c := &list[i]
+		if c.Name != SessionCookie || c.Value == "" {
+			continue
+		}
+		match := c.Domain == "" || domainMatch(host, c.Domain)
+		if best == nil || match || !bestMatch {
+			best, bestMatch = c, match
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("%w（%s，共 %d 条 Cookie）", ErrNotFound, format, len(list))
+	}
+
+	imp := &Import{Format: format, Value: best.Value, Domain: best.Domain, Found: len(list)}
+	var expires time.Time
+	for _, c := range list {
+		if c.Name == SessionCookie && c.Value == best.Value && c.Expires.After(expires) {
+			expires = c.Expires
+		}
+	}
+	if !expires.IsZero() {
+		if !expires.After(clock.Now()) {
+			return nil, fmt.Errorf("%w（%s）", ErrExpired, clock.FormatRFC3339(expires))
+		}
+		imp.Expires = &expires
+	}
+	return imp, nil
+}
+
+// Parse 识别格式并解析出全部 Cookie
+func Parse(data []byte) (string, []Cookie, error) {
+	data = bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	if len(data) == 0 {
+		return "", nil, fmt.Errorf("%w: 内容为空", ErrUnknownFormat)
+	}
+
+	switch {
+	case data[0] == '{':
+		list, err := parseHAR(data)
+		return FormatHAR, list, err
+	case data[0] == '[':
+		list, err := parseJSON(data)
+		return FormatJSON, list, err
+	case isNetscape(data):
+		list, err := parseNetscape(data)
+		return FormatNetscape, list, err
+	case isValue(data):
+		return FormatValue, []Cookie{{Name: SessionCookie, Value: string(data)}}, nil
+	case bytes.ContainsRune(data, '='):
+		return FormatHeader, parseHeader(string(data)), nil
+	default:
+		return "", nil, ErrUnknownFormat
+	}
+}
+
+// isValue 只有一个值：不含空白与分号，= 只能作为末尾的 base64 填充出现
+func isValue(data []byte) bool {
+	if bytes.ContainsAny(data, " \t\r\n;") {
+		return false
+	}
+	name := bytes.TrimRight(data, "=")
+	return len(name) > 0 && !bytes.ContainsRune(name, '=') && string(name) != SessionCookie
+}
+
+// isNetscape 以 Netscape 文件头开头，或存在 7 列制表符分隔的行
+func isNetscape(data []byte) bool {
+	if bytes.HasPrefix(data, []byte("# Netscape HTTP Cookie File")) || bytes.HasPrefix(data, []byte("# HTTP Cookie File")) {
+		return true
+	}
+	for line := range strings.Lines(string(data)) {
+		if len(strings.Split(strings.TrimRight(line, "\r\n"), "\t")) == 7 {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetscape 解析 cookies.txt：domain、include_subdomains、path、secure、expiry、name、value
+func parseNetscape(data []byte) ([]Cookie, error) {
+	var list []Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// curl 与浏览器插件将 HttpOnly Cookie 写为 #HttpOnly_ 前缀
+		line = strings.TrimPrefix(line, "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		c := Cookie{Domain: fields[0], Name: fields[5], Value: fields[6]}
+		if sec, err := strconv.ParseInt(fields[4], 10, 64); err == nil && sec > 0 {
+			c.Expires = time.Unix(sec, 0)
+		}
+		list = append(list, c)
+	}
+	return list, scanner.Err()
+}
+
+// jsonCookie EditThisCookie / Cookie-Editor 导出的一条 Cookie
+type jsonCookie struct {
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Domain         string  `json:"domain"`
+	ExpirationDate float64 `json:"expirationDate"`
+	Session        bool    `json:"session"`
+}
+
+// parseJSON 解析 EditThisCookie 格式的 JSON 数组
+func parseJSON(data []byte) ([]Cookie, error) {
+	var raw []jsonCookie
+	if err := sonic.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%w: 解析 JSON 失败: %v", ErrUnknownFormat, err)
+	}
+	list := make([]Cookie, 0, len(raw))
+	for _, r := range raw {
+		c := Cookie{Name: r.Name, Value: r.Value, Domain: r.Domain}
+		if !r.Session && r.ExpirationDate > 0 {
+			c.Expires = time.Unix(int64(r.ExpirationDate), 0)
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// harCookie HAR 中的一条 Cookie
+type harCookie struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	Domain  string `json:"domain"`
+	Expires string `json:"expires"`
+}
+
+// harHeader HAR 中的一个请求头
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// harFile HAR 中用到的字段
+type harFile struct {
+	Log *struct {
+		Entries []struct {
+			Request struct {
+				URL     string      `json:"url"`
+				Cookies []harCookie `json:"cookies"`
+				Headers []harHeader `json:"headers"`
+			} `json:"request"`
+			Response struct {
+				Cookies []harCookie `json:"cookies"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+// parseHAR 解析 HAR 中各请求的 Cookie 与响应的 Set-Cookie，没有域名时取请求的主机名
+func parseHAR(data []byte) ([]Cookie, error) {
+	var har harFile
+	if err := sonic.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("%w: 解析 HAR 失败: %v", ErrUnknownFormat, err)
+	}
+	if har.Log == nil {
+		return nil, fmt.Errorf("%w: JSON 对象中没有 HAR 的 log 字段", ErrUnknownFormat)
+	}
+
+	var list []Cookie
+	add := func(hc harCookie, host string) {
+		c := Cookie{Name: hc.Name, Value: hc.Value, Domain: hc.Domain}
+		if c.Domain == "" {
+			c.Domain = host
+		}
+		if t, err := time.Parse(time.RFC3339, hc.Expires); err == nil {
+			c.Expires = t
+		}
+		list = append(list, c)
+	}
+	for _, e := range har.Log.Entries {
+		host := ""
+		if u, err := url.Parse(e.Request.URL); err == nil {
+			host = u.Hostname()
+		}
+		if len(e.Request.Cookies) > 0 {
+			for _, hc := range e.Request.Cookies {
+				add(hc, host)
+			}
+		} else {
+			// 部分浏览器导出时省略 cookies 数组，只保留请求头
+			for _, h := range e.Request.Headers {
+				if strings.EqualFold(h.Name, "cookie") {
+					for _, c := range parseHeader(h.Value) {
+						c.Domain = host
+						list = append(list, c)
+					}
+				}
+			}
+		}
+		for _, hc := range e.Response.Cookies {
+			add(hc, host)
+		}
+	}
+	return list, nil
+}
+
+// parseHeader 解析 Cookie 请求头 "a=1; b=2"
+func parseHeader(header string) []Cookie {
+	header = strings.TrimSpace(header)
+	if name, rest, ok := strings.Cut(header, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "cookie") {
+		header = rest
+	}
+	var list []Cookie
+	for part := range strings.SplitSeq(header, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || name == "" {
+			continue
+		}
+		list = append(list, Cookie{Name: strings.TrimSpace(name), Value: strings.Trim(strings.TrimSpace(value), `"`)})
+	}
+	return list
+}
+
+// hostname 去掉端口
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// domainMatch host 是否属于 Cookie 的域名（RFC 6265 的域名匹配，忽略开头的点）
+func domainMatch(host, domain string) bool {
+	host = strings.ToLower(host)
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,132 @@
+package cookies
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"llmaget/clock"
+)
+
+// testHost 石之家接口域名
+const testHost = "apiff14risingstones.web.sdo.com:443"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format string
+		count  int
+		err    error
+	}{
+		{"Netscape", "# Netscape HTTP Cookie File\n.sdo.com\tTRUE\t/\tFALSE\t0\ta\t1\n", FormatNetscape, 1, nil},
+		{"Netscape 无文件头", ".sdo.com\tTRUE\t/\tFALSE\t0\ta\t1\n#HttpOnly_.sdo.com\tTRUE\t/\tTRUE\t0\tb\t2", FormatNetscape, 2, nil},
+		{"HAR", `{"log":{"entries":[]}}`, FormatHAR, 0, nil},
+		{"不是 HAR 的 JSON 对象", `{"a":1}`, FormatHAR, 0, ErrUnknownFormat},
+		{"JSON 数组", `[{"name":"a","value":"1"}]`, FormatJSON, 1, nil},
+		{"JSON 无效", `[{"name":}]`, FormatJSON, 0, ErrUnknownFormat},
+		{"请求头", "Cookie: a=1; b=2", FormatHeader, 2, nil},
+		{"单条请求头", "ff14risingstones=abc", FormatHeader, 1, nil},
+		{"值", "abc123", FormatValue, 1, nil},
+		{"带 BOM 与空白的值", "\xef\xbb\xbf abc123\n", FormatValue, 1, nil},
+		{"base64 填充的值", "YWJjZA==", FormatValue, 1, nil},
+		{"空内容", "  \n", "", 0, ErrUnknownFormat},
+		{"无法识别", "hello world", "", 0, ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, list, err := Parse([]byte(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Parse() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if format != tt.format || len(list) != tt.count {
+				t.Errorf("Parse() = %s %d 条, want %s %d 条 (%v)", format, len(list), tt.format, tt.count, list)
+			}
+		})
+	}
+}
+
+func TestExtract(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, clock.Location())
+	defer clock.Set(clock.Fixed(now))()
+	future := now.Add(30 * 24 * time.Hour).Truncate(time.Second)
+	past := now.Add(-time.Hour)
+
+	netscape := "# Netscape HTTP Cookie File\n" +
+		".sdo.com\tTRUE\t/\tFALSE\t0\tother\tx\n" +
+		"#HttpOnly_.web.sdo.com\tTRUE\t/\tTRUE\t" + unix(future) + "\tff14risingstones\tnet-value\n"
+	harWithCookies := `{"log":{"entries":[{
+		"request":{"url":"https://apiff14risingstones.web.sdo.com/api/x","cookies":[{"name":"ff14risingstones","value":"har-value"}],
+			"headers":[{"name":"Cookie","value":"ff14risingstones=header-value"}]},
+		"response":{"cookies":[{"name":"ff14risingstones","value":"har-value","expires":"` + future.UTC().Format(time.RFC3339) + `"}]}
+	}]}}`
+	harHeaders := `{"log":{"entries":[{
+		"request":{"url":"https://apiff14risingstones.web.sdo.com/api/x","headers":[{"name":"cookie","value":"a=1; ff14risingstones=header-value"}]},
+		"response":{}
+	}]}}`
+	editThisCookie := `[
+		{"name":"ff14risingstones","value":"session","domain":".web.sdo.com","session":true},
+		{"name":"ff14risingstones","value":"json-value","domain":".web.sdo.com","expirationDate":` + unix(future) + `.5}
+	]`
+
+	tests := []struct {
+		name    string
+		data    string
+		format  string
+		value   string
+		domain  string
+		expires *time.Time
+		found   int
+		err     error
+	}{
+		{"Netscape 含 #HttpOnly_", netscape, FormatNetscape, "net-value", ".web.sdo.com", &future, 2, nil},
+		{"HAR cookies 数组与 Set-Cookie 过期时间", harWithCookies, FormatHAR, "har-value", "apiff14risingstones.web.sdo.com", &future, 2, nil},
+		{"HAR 没有 cookies 数组", harHeaders, FormatHAR, "header-value", "apiff14risingstones.web.sdo.com", nil, 2, nil},
+		{"EditThisCookie 同等条件取最后一条", editThisCookie, FormatJSON, "json-value", ".web.sdo.com", &future, 2, nil},
+		{"Cookie 请求头", `Cookie: a=1; ff14risingstones="quoted"`, FormatHeader, "quoted", "", nil, 2, nil},
+		{"值", "plain-value", FormatValue, "plain-value", "", nil, 1, nil},
+		{"base64 填充的值", "YWJjZGU=", FormatValue, "YWJjZGU=", "", nil, 1, nil},
+		{"优先匹配域名", `[
+			{"name":"ff14risingstones","value":"match","domain":".sdo.com"},
+			{"name":"ff14risingstones","value":"other","domain":"ff14.example.com"}
+		]`, FormatJSON, "match", ".sdo.com", nil, 2, nil},
+		{"没有匹配域名时取最后一条", `[
+			{"name":"ff14risingstones","value":"a","domain":"a.example.com"},
+			{"name":"ff14risingstones","value":"b","domain":"b.example.com"}
+		]`, FormatJSON, "b", "b.example.com", nil, 2, nil},
+		{"已过期", ".web.sdo.com\tTRUE\t/\tTRUE\t" + unix(past) + "\tff14risingstones\told", FormatNetscape, "", "", nil, 0, ErrExpired},
+		{"没有 ff14risingstones", "Cookie: a=1; b=2", FormatHeader, "", "", nil, 0, ErrNotFound},
+		{"其他 Cookie 的单条请求头", "other=abc", FormatHeader, "", "", nil, 0, ErrNotFound},
+		{"空值", `[{"name":"ff14risingstones","value":""}]`, FormatJSON, "", "", nil, 0, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp, err := Extract([]byte(tt.data), testHost)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Extract() error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if imp.Format != tt.format || imp.Value != tt.value || imp.Domain != tt.domain || imp.Found != tt.found {
+				t.Errorf("Extract() = %s %q %q %d, want %s %q %q %d",
+					imp.Format, imp.Value, imp.Domain, imp.Found, tt.format, tt.value, tt.domain, tt.found)
+			}
+			switch {
+			case tt.expires == nil && imp.Expires != nil:
+				t.Errorf("Expires = %v, want nil", imp.Expires)
+			case tt.expires != nil && (imp.Expires == nil || !imp.Expires.Equal(*tt.expires)):
+				t.Errorf("Expires = %v, want %v", imp.Expires, tt.expires)
+			}
+		})
+	}
+}
+
+// unix 秒级时间戳
+func unix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
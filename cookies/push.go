@@ -0,0 +1,81 @@
+package cookies
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+)
+
+// FormatBookmarklet 通过书签从石之家页面推送的 Cookie
+const FormatBookmarklet = "bookmarklet"
+
+// PushPath 书签推送 Cookie 的接口路径
+const PushPath = "/llmaget/config/push-cookie"
+
+// PushTokens 书签推送使用的一次性令牌，只保存在内存中，重启后失效
+type PushTokens struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+}
+
+// NewPushTokens 创建令牌表
+func NewPushTokens() *PushTokens {
+	return &PushTokens{tokens: make(map[string]time.Time)}
+}
+
+// Issue 签发有效期为 ttl 的令牌，同时清理已过期的令牌
+func (p *PushTokens) Issue(ttl time.Duration) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	now := clock.Now()
+	expires := now.Add(ttl)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for t, exp := range p.tokens {
+		if !exp.After(now) {
+			delete(p.tokens, t)
+		}
+	}
+	p.tokens[token] = expires
+	return token, expires, nil
+}
+
+// Consume 校验并作废令牌，令牌不存在或已过期时返回 false
+func (p *PushTokens) Consume(token string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for t, exp := range p.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			delete(p.tokens, t)
+			return exp.After(clock.Now())
+		}
+	}
+	return false
+}
+
+// Bookmarklet 生成书签地址：在石之家页面读取 document.cookie，连同令牌推送到 base 上的 llmaget
+//
+// 请求体为 text/plain 的 JSON，属于简单请求，浏览器不会先发送预检请求。
+func Bookmarklet(base, token string) string {
+	endpoint, _ := sonic.MarshalString(strings.TrimRight(base, "/") + PushPath)
+	tok, _ := sonic.MarshalString(token)
+	script := `(function(){var c=document.cookie;` +
+		`if(c.indexOf('` + SessionCookie + `=')<0){alert('未读取到 ` + SessionCookie + `，请确认已登录石之家；若仍失败请改用导入');return;}` +
+		`fetch(` + endpoint + `,{method:'POST',headers:{'Content-Type':'text/plain'},body:JSON.stringify({token:` + tok + `,cookie:c})})` +
+		`.then(function(r){return r.json();})` +
+		`.then(function(r){alert((r.code===10000&&r.data.session.valid?'✅ ':'⚠️ ')+r.msg);})` +
+		`.catch(function(e){alert('❌ 推送失败：'+e);});})();`
+	return "javascript:" + url.PathEscape(script)
+}
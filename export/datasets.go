@@ -0,0 +1,224 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/jobs"
+	"llmaget/models"
+	"llmaget/services"
+	"llmaget/shop"
+)
+
+// 可导出的数据集
+const (
+	DatasetSnapshots = "snapshots"
+	DatasetLedger    = "ledger"
+	DatasetRewards   = "rewards"
+	DatasetWatchlist = "watchlist"
+)
+
+// ErrUnknownDataset 不支持的数据集
+var ErrUnknownDataset = errors.New("不支持的数据集，可选 snapshots、ledger、rewards、watchlist")
+
+// Sources 导出数据来源
+type Sources struct {
+	Snapshots *services.SnapshotLog
+	Rewards   *services.RewardHistory
+	Ledger    *jobs.Ledger
+	Shop      *services.ShopLog
+	// Watch 关注的商品名称或 ID，即 settings.shop_watch
+	Watch []string
+}
+
+// dataset 数据集定义，rows 按过滤条件逐行输出
+type dataset struct {
+	columns []string
+	rows    func(src Sources, f Filter, emit func([]any) error) error
+}
+
+var datasets = map[string]dataset{
+	DatasetSnapshots: {
+		columns: []string{"time", "account", "uuid", "character_name", "area_name", "group_name", "play_time", "play_time_minutes", "job_levels", "achievements"},
+		rows:    snapshotRows,
+	},
+	DatasetLedger: {
+		columns: []string{"id", "kind", "account", "trigger", "status", "created_at", "finished_at", "error", "result"},
+		rows:    ledgerRows,
+	},
+	DatasetRewards: {
+		columns: []string{"month", "account", "id", "item_name", "num", "rule", "begin_date", "end_date", "claimed_at"},
+		rows:    rewardRows,
+	},
+	DatasetWatchlist: {
+		columns: []string{"time", "account", "points", "id", "goods_name", "integral", "stock", "affordable"},
+		rows:    watchlistRows,
+	},
+}
+
+// Datasets 列出可导出的数据集名称
+func Datasets() []string {
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup 校验数据集名称
+func Lookup(name string) error {
+	if _, ok := datasets[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDataset, name)
+	}
+	return nil
+}
+
+// Export 将数据集按过滤条件写入 w，返回写出的行数
+func Export(w Writer, src Sources, name string, f Filter) (int, error) {
+	ds, ok := datasets[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnknownDataset, name)
+	}
+	if err := w.Header(ds.columns); err != nil {
+		return 0, err
+	}
+	n := 0
+	err := ds.rows(src, f, func(values []any) error {
+		n++
+		return w.Row(values)
+	})
+	if err != nil {
+		w.Close()
+		return n, err
+	}
+	return n, w.Close()
+}
+
+// snapshotRows 角色信息快照，按记录顺序输出
+func snapshotRows(src Sources, f Filter, emit func([]any) error) error {
+	if src.Snapshots == nil {
+		return nil
+	}
+	return src.Snapshots.Each(func(s models.Snapshot) error {
+		if !f.Match(s.Time, s.Account) {
+			return nil
+		}
+		return emit([]any{
+			s.Time, s.Account, s.UUID, s.CharacterName, s.AreaName, s.GroupName,
+			s.PlayTime, s.PlayTimeMinutes, jobLevels(s.JobLevels), strings.Join(s.Achievements, ";"),
+		})
+	})
+}
+
+// ledgerRows 签到流水，取 sign_in、sign_and_claim、reward_sweep 任务，按结束顺序输出
+func ledgerRows(src Sources, f Filter, emit func([]any) error) error {
+	if src.Ledger == nil {
+		return nil
+	}
+	return src.Ledger.Each(func(job jobs.Job) error {
+		if !f.Match(job.CreatedAt, job.Account) {
+			return nil
+		}
+		var finished any
+		if job.FinishedAt != nil {
+			finished = *job.FinishedAt
+		}
+		var result string
+		if job.Result != nil {
+			data, _ := sonic.Marshal(job.Result)
+			result = string(data)
+		}
+		return emit([]any{
+			job.ID, job.Kind, job.Account, job.Trigger, string(job.Status),
+			job.CreatedAt, finished, job.Error, result,
+		})
+	})
+}
+
+// rewardRows 已领取的签到奖励，按月份正序输出
+//
+// 奖励历史只保存默认账号；非本服务领取的奖励没有领取时间，按所在月份的第一天参与日期过滤。
+func rewardRows(src Sources, f Filter, emit func([]any) error) error {
+	if src.Rewards == nil {
+		return nil
+	}
+	months, err := src.Rewards.List()
+	if err != nil {
+		return err
+	}
+	for i := len(months) - 1; i >= 0; i-- {
+		m := months[i]
+		monthStart, _ := time.ParseInLocation(services.MonthLayout, m.Month, clock.Location())
+		for _, r := range m.Rewards {
+			if r.IsGet != 1 {
+				continue
+			}
+			at := monthStart
+			var claimed any
+			if r.ClaimedAt != nil {
+				at = *r.ClaimedAt
+				claimed = *r.ClaimedAt
+			}
+			if !f.Match(at, config.DefaultAccount) {
+				continue
+			}
+			if err := emit([]any{
+				m.Month, config.DefaultAccount, r.ID, r.ItemName, r.Num, r.Rule,
+				r.BeginDate, r.EndDate, claimed,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// watchlistRows 关注商品在每次积分商城检查中的价格、库存与可兑换状态，按记录顺序输出
+//
+// 关注列表取导出时的 shop_watch，而不是检查当时的设置。
+func watchlistRows(src Sources, f Filter, emit func([]any) error) error {
+	if src.Shop == nil || len(src.Watch) == 0 {
+		return nil
+	}
+	return src.Shop.Each(func(rec models.ShopRecord) error {
+		if !f.Match(rec.Time, rec.Account) {
+			return nil
+		}
+		for _, it := range shop.NewState(rec, nil, src.Watch).Items {
+			if !it.Watched {
+				continue
+			}
+			if err := emit([]any{
+				rec.Time, rec.Account, rec.Points, it.ID, it.Name, it.Cost, it.Stock, it.Affordable,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// jobLevels 职业等级拼接为 "职业:等级" 列表，按职业名排序
+func jobLevels(levels map[string]string) string {
+	if len(levels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(levels))
+	for k := range levels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + ":" + levels[k]
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,212 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+)
+
+// 导出格式
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// DateLayout 日期过滤参数格式
+const DateLayout = "2006-01-02"
+
+var (
+	// ErrUnknownFormat 不支持的导出格式
+	ErrUnknownFormat = errors.New("不支持的导出格式，可选 csv、jsonl、xlsx")
+	// ErrInvalidRange 日期范围参数错误
+	ErrInvalidRange = errors.New("日期格式应为 2006-01-02 或 RFC3339")
+)
+
+// Filter 导出过滤条件，零值表示不限制
+type Filter struct {
+	From    time.Time
+	To      time.Time
+	Account string
+}
+
+// Match 判断记录是否满足过滤条件，To 不包含在内
+func (f Filter) Match(t time.Time, account string) bool {
+	if !f.From.IsZero() && t.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.Before(f.To) {
+		return false
+	}
+	return f.Account == "" || f.Account == account
+}
+
+// ParseFilter 解析过滤参数，日期按业务时区解析，to 为日期时包含当天
+func ParseFilter(from, to, account string) (Filter, error) {
+	f := Filter{Account: account}
+	var err error
+	if from != "" {
+		if f.From, err = parseTime(from, false); err != nil {
+			return Filter{}, err
+		}
+	}
+	if to != "" {
+		if f.To, err = parseTime(to, true); err != nil {
+			return Filter{}, err
+		}
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && !f.From.Before(f.To) {
+		return Filter{}, fmt.Errorf("%w: from 必须早于 to", ErrInvalidRange)
+	}
+	return f, nil
+}
+
+func parseTime(v string, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(DateLayout, v, clock.Location()); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidRange, v)
+}
+
+// Writer 按行写出表格数据
+type Writer interface {
+	Header(columns []string) error
+	Row(values []any) error
+	Close() error
+}
+
+// NewWriter 创建指定格式的写出器，数据边生成边写入 w
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}, nil
+	case FormatJSONL:
+		return &jsonlWriter{w: bufio.NewWriter(w)}, nil
+	case FormatXLSX:
+		return newXLSXWriter(w), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// ContentType 导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// formatValue 将单元格值转换为文本
+func formatValue(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case time.Time:
+		return clock.FormatRFC3339(x)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// csvWriter CSV 写出器，首行为表头
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func (c *csvWriter) Header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(values []any) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+	// 定期刷新，避免大量数据堆积在缓冲区
+	if c.rows++; c.rows%100 == 0 {
+		c.w.Flush()
+	}
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonlWriter JSON Lines 写出器，每行一个对象，字段顺序与表头一致
+type jsonlWriter struct {
+	w       *bufio.Writer
+	columns []string
+}
+
+func (j *jsonlWriter) Header(columns []string) error {
+	j.columns = columns
+	return nil
+}
+
+func (j *jsonlWriter) Row(values []any) error {
+	j.w.WriteByte('{')
+	for i, col := range j.columns {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		key, _ := sonic.Marshal(col)
+		j.w.Write(key)
+		j.w.WriteByte(':')
+
+		var v any
+		if i < len(values) {
+			v = values[i]
+		}
+		if t, ok := v.(time.Time); ok {
+			v = clock.FormatRFC3339(t)
+		}
+		val, err := sonic.Marshal(v)
+		if err != nil {
+			return err
+		}
+		j.w.Write(val)
+	}
+	j.w.WriteByte('}')
+	return j.w.WriteByte('\n')
+}
+
+func (j *jsonlWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/backup"
+	"llmaget/clock"
+	"llmaget/models"
+)
+
+// PassphraseHeader 备份加密密码请求头
+const PassphraseHeader = "X-Backup-Passphrase"
+
+// adminAuth 管理接口鉴权，令牌通过 Authorization: Bearer <admin_token> 传递
+func (h *Handler) adminAuth(c *gin.Context) {
+	token := h.state.Settings().AdminToken
+	if token == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, models.NewError(403, "未配置 admin_token，管理接口已禁用"))
+		return
+	}
+	got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, models.NewError(401, "管理令牌无效"))
+		return
+	}
+	c.Next()
+}
+
+// Backup 下载配置与状态数据的备份归档，携带 X-Backup-Passphrase 时加密
+// @Summary 下载备份
+// @Router /llmaget/admin/backup [get]
+func (h *Handler) Backup(c *gin.Context) {
+	// 先完整生成再发送，失败时仍能返回错误响应
+	var buf bytes.Buffer
+	m, err := backup.Create(&buf, h.state.Settings(), c.GetHeader(PassphraseHeader))
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "❌ 创建备份失败", "error", err)
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "创建备份失败"))
+		return
+	}
+
+	name := "llmaget-" + clock.Now().Format("20060102-150405") + ".tar.gz"
+	if m.Encrypted {
+		name += ".enc"
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+name+`"`)
+	c.Data(http.StatusOK, "application/octet-stream", buf.Bytes())
+	slog.InfoContext(c.Request.Context(), "📦 备份已下载", "files", len(m.Files), "encrypted", m.Encrypted)
+}
+
+// Restore 上传备份归档并恢复，dry_run=1 时只校验不写入
+//
+// 恢复后配置立即重新加载，任务历史同步到内存；监听端口等参数需重启后生效。
+// @Summary 从备份恢复
+// @Router /llmaget/admin/restore [post]
+func (h *Handler) Restore(c *gin.Context) {
+	dryRun := c.Query("dry_run") == "1"
+	m, err := backup.Restore(c.Request.Body, h.state.Settings(), c.GetHeader(PassphraseHeader), dryRun)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, backup.ErrPassphraseRequired), errors.Is(err, backup.ErrBadPassphrase):
+			status = http.StatusUnauthorized
+		case errors.Is(err, backup.ErrSchemaVersion):
+			status = http.StatusConflict
+		}
+		slog.WarnContext(c.Request.Context(), "⚠️ 备份恢复失败", "dry_run", dryRun, "error", err)
+		c.JSON(status, models.NewError(status, err.Error()))
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, models.NewSuccess("备份校验通过", m))
+		return
+	}
+
+	if err := h.state.Load(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "❌ 恢复后重新加载配置失败", "error", err)
+	}
+	if err := h.jobs.Reload(); err != nil {
+		slog.ErrorContext(c.Request.Context(), "❌ 恢复后重新加载任务历史失败", "error", err)
+	}
+	slog.InfoContext(c.Request.Context(), "♻️ 已从备份恢复", "files", len(m.Files), "created_at", m.CreatedAt, "host", m.Host)
+	c.JSON(http.StatusOK, models.NewSuccess("恢复成功，监听端口等参数需重启后生效", m))
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/bytedance/sonic"
+	"github.com/gin-gonic/gin"
+
+	"llmaget/config"
+	"llmaget/cookies"
+	"llmaget/models"
+)
+
+// maxCookieImportSize 导入内容的大小上限，HAR 文件包含响应内容时可能较大
+const maxCookieImportSize = 64 << 20
+
+// maxCookiePushSize 书签推送请求体的大小上限
+const maxCookiePushSize = 64 << 10
+
+// ImportCookie 从 cookies.txt、HAR、EditThisCookie JSON 或 Cookie 请求头中导入 ff14risingstones，并立即检查会话
+// @Summary 导入 Cookie
+// @Router /llmaget/config/import-cookie [post]
+func (h *Handler) ImportCookie(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCookieImportSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "读取请求体失败: "+err.Error()))
+		return
+	}
+	imp, err := cookies.Extract(data, h.state.Settings().BaseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	h.applyCookie(c, imp)
+}
+
+// applyCookie 保存 Cookie、检查会话并写入响应
+func (h *Handler) applyCookie(c *gin.Context, imp *cookies.Import) {
+	res, err := cookies.Apply(mutatingContext(c), imp, h.sites)
+	if err != nil {
+		if errors.Is(err, config.ErrInvalidConfig) {
+			c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "保存配置失败: "+err.Error()))
+		return
+	}
+
+	msg := "Cookie 已导入，会话有效"
+	if !res.Session.Valid {
+		msg = "Cookie 已导入，但会话检查未通过: " + res.Session.Error
+	}
+	c.JSON(http.StatusOK, models.NewSuccess(msg, res))
+}
+
+// IssuePushToken 签发一次性令牌并生成推送 Cookie 的书签
+// @Summary 生成 Cookie 推送书签
+// @Router /llmaget/config/push-token [post]
+func (h *Handler) IssuePushToken(c *gin.Context) {
+	st := h.state.Settings()
+	token, expires, err := h.pushTokens.Issue(st.CookiePushTTL.D())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "生成令牌失败: "+err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", models.CookiePushToken{
+		Bookmarklet: cookies.Bookmarklet(requestBase(c), token),
+		ExpiresAt:   expires,
+		Origins:     st.CookiePushOriginList(),
+	}))
+}
+
+// PushCookie 接收书签从石之家页面推送的 Cookie，校验来源与一次性令牌后保存并检查会话
+//
+// 跨域响应头由全局 CORS 中间件添加，这里以浏览器无法伪造的 Origin 限制调用来源。
+// @Summary 书签推送 Cookie
+// @Router /llmaget/config/push-cookie [post]
+func (h *Handler) PushCookie(c *gin.Context) {
+	origin := c.GetHeader("Origin")
+	if !slices.Contains(h.state.Settings().CookiePushOriginList(), origin) {
+		slog.WarnContext(c.Request.Context(), "⚠️ 拒绝来源不允许的 Cookie 推送", "origin", origin)
+		c.JSON(http.StatusForbidden, models.NewError(403, "不允许的来源: "+origin))
+		return
+	}
+
+	var req models.CookiePushRequest
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxCookiePushSize))
+	if err == nil {
+		err = sonic.Unmarshal(data, &req)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "请求格式错误: "+err.Error()))
+		return
+	}
+	if !h.pushTokens.Consume(req.Token) {
+		c.JSON(http.StatusUnauthorized, models.NewError(401, "令牌无效或已过期，请在配置页重新生成书签"))
+		return
+	}
+	imp, err := cookies.Extract([]byte(req.Cookie), h.state.Settings().BaseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	imp.Format = cookies.FormatBookmarklet
+	h.applyCookie(c, imp)
+}
+
+// requestBase 客户端访问本服务使用的地址，经反向代理时以 X-Forwarded-Proto、X-Forwarded-Host 为准
+func requestBase(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	host := c.Request.Host
+	if fwd := c.GetHeader("X-Forwarded-Host"); fwd != "" {
+		host = fwd
+	}
+	return scheme + "://" + host
+}
@@ -1,29 +1,50 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"llmaget/clock"
 	"llmaget/config"
+	"llmaget/cookies"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/mcpserver"
 	"llmaget/models"
 	"llmaget/services"
+	"llmaget/sites"
+	"llmaget/tools"
 )
 
 // Handler HTTP 处理器
 type Handler struct {
 	ff14Svc *services.FF14Service
+	jobs    *jobs.Manager
 	state   *config.AppState
+	tools   *tools.Registry
+	sites   *sites.Registry
+	// pushTokens 书签推送 Cookie 的一次性令牌
+	pushTokens *cookies.PushTokens
 }
 
 // NewHandler 创建处理器实例
-func NewHandler(ff14Svc *services.FF14Service) *Handler {
+func NewHandler(ff14Svc *services.FF14Service, jobMgr *jobs.Manager, siteReg *sites.Registry) *Handler {
 	return &Handler{
 		ff14Svc: ff14Svc,
+		jobs:    jobMgr,
 		state:   config.GetState(),
+		tools:   tools.NewRegistry(ff14Svc, jobMgr),
+		sites:   siteReg,
+
+		pushTokens: cookies.NewPushTokens(),
 	}
 }
 
@@ -36,13 +57,51 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 		api.GET("/refresh", h.Refresh)
 		api.GET("/sign_in", h.SignIn)
 		api.GET("/config", h.GetConfig)
+		api.GET("/config/effective", h.GetEffectiveConfig)
+		// 替换 Cookie 的接口与配置页一样不鉴权，push-cookie 依靠一次性令牌与 Origin 校验
 		api.POST("/config", h.UpdateConfig)
+		api.POST("/config/import-cookie", h.ImportCookie)
+		api.POST("/config/push-token", h.IssuePushToken)
+		api.POST("/config/push-cookie", h.PushCookie)
 		api.GET("/set", h.SetConfigPage)
 		api.GET("/search", h.SearchUserInfo)
+		api.GET("/profile", h.GetProfile)
 		api.GET("/get_sign_reward", h.GetSignReward)
 		api.GET("/sign_reward_list", h.SignRewardList)
 		api.GET("/sign_and_get_sign_reward", h.SignAndGetSignReward)
+		api.GET("/claim_rewards", h.ClaimRewards)
+		api.GET("/reward_sweep", h.RewardSweep)
+		api.GET("/rewards/history", h.RewardHistory)
+		api.GET("/missions", h.MissionList)
+		api.POST("/missions/run", h.RunMissions)
+		api.GET("/shop", h.GetShop)
+		api.POST("/shop/check", h.CheckShop)
+		api.GET("/shop/dashboard", h.ShopDashboard)
+		api.GET("/points/history", h.PointsHistory)
+		api.GET("/jobs", h.ListJobs)
+		api.GET("/jobs/:id", h.GetJob)
+		api.GET("/export/:dataset", h.Export)
+		api.GET("/tools", h.ListTools)
+		// 工具可修改账号状态，与 MCP 接口使用同一令牌
+		api.POST("/tools/call", h.adminAuth, h.CallTool)
+		api.GET("/recap", h.PreviewRecap)
+		api.POST("/recap/send", h.SendRecap)
+		api.GET("/sites", h.ListSites)
+		api.GET("/sites/:site/session", h.CheckSiteSession)
+		api.GET("/sites/:site/info", h.GetSiteInfo)
+		api.POST("/sites/:site/tasks/:task", h.RunSiteTask)
+	}
+
+	admin := api.Group("/admin", h.adminAuth)
+	{
+		admin.GET("/backup", h.Backup)
+		admin.POST("/restore", h.Restore)
 	}
+
+	// MCP Streamable HTTP 传输，工具可修改账号状态，与管理接口使用同一令牌，与 /tools/call 使用同一白名单
+	api.Any("/mcp", h.adminAuth, gin.WrapH(mcpserver.HTTPHandler(h.tools, h.agentTools)))
+
+	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 }
 
 // GetFFInfo 获取 FF14 角色信息
@@ -58,7 +117,7 @@ func (h *Handler) GetFFInfo(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccess("success", data))
 }
 
-// 领取签到奖励
+// 领取签到奖励，month 为空时取当月
 func (h *Handler) GetSignReward(c *gin.Context) {
 	idStr := c.Query("id")
 	id, err := strconv.Atoi(idStr)
@@ -66,52 +125,187 @@ func (h *Handler) GetSignReward(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewError(400, "错误的请求参数"))
 		return
 	}
+	month, ok := h.queryMonth(c)
+	if !ok {
+		return
+	}
 
-	data, err := h.ff14Svc.GetSignReward(id)
+	data, err := h.ff14Svc.GetSignReward(mutatingContext(c), id, month)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "获取数据发生错误"))
+		upstreamError(c, err, "获取数据发生错误")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.NewSuccess("success", string(data)))
 }
 
-// 获取签到奖励列表
+// 获取签到奖励列表，month 为空时取当月
 func (h *Handler) SignRewardList(c *gin.Context) {
-	data, err := h.ff14Svc.SignRewardList()
+	month, ok := h.queryMonth(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.ff14Svc.SignRewardList(c.Request.Context(), month)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "获取数据发生错误"))
+		upstreamError(c, err, "获取数据发生错误")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.NewSuccess("success", data))
 }
 
-// 签到并领取奖励
-func (h *Handler) SignAndGetSignReward(c *gin.Context) {
-	result, err := h.ff14Svc.SignAndGetSignReward()
+// ClaimRewards 领取指定月份所有可领取的奖励，month 为空时取当月
+// @Summary 领取指定月份的奖励
+// @Router /llmaget/claim_rewards [get]
+func (h *Handler) ClaimRewards(c *gin.Context) {
+	month, ok := h.queryMonth(c)
+	if !ok {
+		return
+	}
+
+	data, err := h.ff14Svc.ClaimRewards(mutatingContext(c), month)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "签到并领取奖励过程中发生错误"))
+		upstreamError(c, err, "领取奖励过程中发生错误")
 		return
 	}
 
-	var data any
-	if err := sonic.Unmarshal(result, &data); err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "解析响应失败"))
+	var result map[string][]string
+	if err := sonic.Unmarshal(data, &result); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "解析结果失败"))
 		return
 	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", result))
+}
+
+// RewardSweep 立即执行一次月末扫尾领取
+// @Summary 月末扫尾领取奖励
+// @Router /llmaget/reward_sweep [get]
+func (h *Handler) RewardSweep(c *gin.Context) {
+	h.runJob(c, jobs.KindRewardSweep, "扫尾领取奖励过程中发生错误")
+}
 
+// MissionList 获取每日社区任务及完成情况
+// @Summary 获取社区任务列表
+// @Router /llmaget/missions [get]
+func (h *Handler) MissionList(c *gin.Context) {
+	data, err := h.ff14Svc.MissionList(c.Request.Context())
+	if err != nil {
+		upstreamError(c, err, "获取社区任务发生错误")
+		return
+	}
 	c.JSON(http.StatusOK, models.NewSuccess("success", data))
 }
 
+// RunMissions 立即完成已开启动作的社区任务并领取积分，支持 async=1
+// @Summary 执行社区任务
+// @Router /llmaget/missions/run [post]
+func (h *Handler) RunMissions(c *gin.Context) {
+	h.runJob(c, jobs.KindMissions, "执行社区任务过程中发生错误")
+}
+
+// RewardHistory 获取已保存的每月奖励表，指定 month 时只返回该月
+// @Summary 获取签到奖励历史
+// @Router /llmaget/rewards/history [get]
+func (h *Handler) RewardHistory(c *gin.Context) {
+	history := h.ff14Svc.RewardHistory()
+
+	if c.Query("month") == "" {
+		list, err := history.List()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewError(500, "读取奖励历史失败"))
+			return
+		}
+		c.JSON(http.StatusOK, models.NewSuccess("success", list))
+		return
+	}
+
+	month, ok := h.queryMonth(c)
+	if !ok {
+		return
+	}
+	data, found, err := history.Month(month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "读取奖励历史失败"))
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, models.NewError(404, "该月份没有奖励记录"))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", data))
+}
+
+// Export 导出数据集，结果边生成边写入响应
+// @Summary 导出快照、签到流水或奖励记录
+// @Router /llmaget/export/{dataset} [get]
+func (h *Handler) Export(c *gin.Context) {
+	name := c.Param("dataset")
+	if err := export.Lookup(name); err != nil {
+		c.JSON(http.StatusNotFound, models.NewError(404, err.Error()))
+		return
+	}
+	format := c.DefaultQuery("format", export.FormatCSV)
+	filter, err := export.ParseFilter(c.Query("from"), c.Query("to"), c.Query("account"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	w, err := export.NewWriter(format, c.Writer)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+name+"."+format+`"`)
+	c.Status(http.StatusOK)
+
+	rows, err := export.Export(w, h.exportSources(), name, filter)
+	if err != nil {
+		// 响应头已发出，只能记录日志并中断输出
+		slog.ErrorContext(c.Request.Context(), "❌ 导出失败", "dataset", name, "rows", rows, "error", err)
+		c.Abort()
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "📤 导出完成", "dataset", name, "format", format, "rows", rows)
+}
+
+// exportSources 导出数据来源
+func (h *Handler) exportSources() export.Sources {
+	return export.Sources{
+		Snapshots: h.ff14Svc.Snapshots(),
+		Rewards:   h.ff14Svc.RewardHistory(),
+		Ledger:    h.jobs.Ledger(),
+		Shop:      h.ff14Svc.ShopLog(),
+		Watch:     h.state.Settings().ShopWatchList(),
+	}
+}
+
+// queryMonth 读取并校验 month 参数，校验失败时已写入 400 响应
+func (h *Handler) queryMonth(c *gin.Context) (string, bool) {
+	month, err := services.NormalizeMonth(c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return "", false
+	}
+	return month, true
+}
+
+// 签到并领取奖励
+func (h *Handler) SignAndGetSignReward(c *gin.Context) {
+	h.runJob(c, jobs.KindSignAndClaim, "签到并领取奖励过程中发生错误")
+}
+
 // GetStatus 获取服务状态
 // @Summary 获取服务状态
 // @Router /llmaget/status [get]
 func (h *Handler) GetStatus(c *gin.Context) {
+	fetchInterval := h.state.Settings().FetchInterval.D()
 	lastFetch := h.state.GetLastFetchAt()
 	var nextFetch time.Time
 	if !lastFetch.IsZero() {
-		nextFetch = lastFetch.Add(config.FetchInterval)
+		nextFetch = lastFetch.Add(fetchInterval)
 	}
 
 	data := models.StatusData{
@@ -119,7 +313,10 @@ func (h *Handler) GetStatus(c *gin.Context) {
 		HasCookie:     h.state.HasCookie(),
 		LastFetchAt:   formatTime(lastFetch),
 		NextFetchAt:   formatTime(nextFetch),
-		FetchInterval: config.FetchInterval.String(),
+		FetchInterval: fetchInterval.String(),
+		Breaker:       h.ff14Svc.BreakerStatus(),
+		Cache:         h.ff14Svc.CacheStats(),
+		Sites:         h.sites.Status(),
 	}
 
 	c.JSON(http.StatusOK, models.Response{
@@ -138,9 +335,17 @@ func (h *Handler) Refresh(c *gin.Context) {
 		return
 	}
 
-	go h.ff14Svc.SaveMyBaseInfo()
+	job, deduped, err := h.jobs.Submit(c.Request.Context(), jobs.KindRefresh, config.DefaultAccount, jobs.TriggerManual)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, models.NewError(503, "提交刷新任务失败: "+err.Error()))
+		return
+	}
 
-	c.JSON(http.StatusOK, models.NewSuccess("刷新任务已触发，请稍后查询结果", nil))
+	c.Header("X-Job-ID", job.ID)
+	c.JSON(http.StatusOK, models.NewSuccess("刷新任务已触发，请稍后查询结果", models.JobSubmitData{
+		JobID:   job.ID,
+		Deduped: deduped,
+	}))
 }
 
 // SignIn 执行签到
@@ -152,19 +357,66 @@ func (h *Handler) SignIn(c *gin.Context) {
 		return
 	}
 
-	result, err := h.ff14Svc.SignIn()
+	h.runJob(c, jobs.KindSignIn, "打卡失败")
+}
+
+// runJob 提交任务；async=1 时立即返回任务 ID，否则等待任务结束并返回结果
+func (h *Handler) runJob(c *gin.Context, kind string, failMsg string) {
+	job, deduped, err := h.jobs.Submit(c.Request.Context(), kind, config.DefaultAccount, jobs.TriggerManual)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "打卡失败: "+err.Error()))
+		c.JSON(http.StatusServiceUnavailable, models.NewError(503, "提交任务失败: "+err.Error()))
 		return
 	}
+	c.Header("X-Job-ID", job.ID)
 
-	var data any
-	if err := sonic.Unmarshal(result, &data); err != nil {
-		c.JSON(http.StatusInternalServerError, models.NewError(500, "解析响应失败"))
+	if c.Query("async") == "1" {
+		c.JSON(http.StatusAccepted, models.NewSuccess("任务已提交", models.JobSubmitData{
+			JobID:   job.ID,
+			Deduped: deduped,
+		}))
 		return
 	}
 
-	c.JSON(http.StatusOK, models.NewSuccess("success", data))
+	finished, err := h.jobs.Wait(c.Request.Context(), job.ID)
+	if err != nil {
+		c.JSON(http.StatusAccepted, models.NewSuccess("任务仍在执行，请稍后查询结果", models.JobSubmitData{
+			JobID:   job.ID,
+			Deduped: deduped,
+		}))
+		return
+	}
+	if finished.Status == jobs.StatusFailed {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, failMsg+": "+finished.Error))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccess("success", finished.Result))
+}
+
+// ListJobs 获取最近的任务
+// @Summary 获取最近的任务
+// @Router /llmaget/jobs [get]
+func (h *Handler) ListJobs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "错误的请求参数"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccess("success", h.jobs.List(limit)))
+}
+
+// GetJob 获取任务状态
+// @Summary 获取任务状态
+// @Router /llmaget/jobs/{id} [get]
+func (h *Handler) GetJob(c *gin.Context) {
+	job, ok := h.jobs.Get(c.Param("id"))
+	if !ok {
+		c.JSON(http.StatusNotFound, models.NewError(404, "任务不存在"))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NewSuccess("success", job))
 }
 
 // GetConfig 获取配置
@@ -177,6 +429,13 @@ func (h *Handler) GetConfig(c *gin.Context) {
 	c.JSON(http.StatusOK, models.NewSuccess("success", data))
 }
 
+// GetEffectiveConfig 获取生效的分层配置，Cookie 已脱敏
+// @Summary 获取生效配置
+// @Router /llmaget/config/effective [get]
+func (h *Handler) GetEffectiveConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccess("success", h.state.Effective()))
+}
+
 // UpdateConfig 更新配置
 // @Summary 更新配置
 // @Router /llmaget/config [post]
@@ -191,6 +450,10 @@ func (h *Handler) UpdateConfig(c *gin.Context) {
 		UserAgent: req.UserAgent,
 		Cookie:    req.Cookie,
 	}); err != nil {
+		if errors.Is(err, config.ErrInvalidConfig) {
+			c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+			return
+		}
 		c.JSON(http.StatusInternalServerError, models.NewError(500, "保存配置失败: "+err.Error()))
 		return
 	}
@@ -252,7 +515,7 @@ func (h *Handler) SearchUserInfo(c *gin.Context) {
 	}
 
 	// 执行搜索
-	result, err := h.ff14Svc.SearchUser(name, serverName)
+	result, err := h.ff14Svc.SearchUser(c.Request.Context(), name, serverName, c.Query("refresh") == "1")
 	if err != nil {
 		c.Header("Content-Type", "text/html; charset=utf-8")
 		c.String(http.StatusOK, searchResultPageHTML(name, serverName, nil, err.Error()))
@@ -264,12 +527,46 @@ func (h *Handler) SearchUserInfo(c *gin.Context) {
 	c.String(http.StatusOK, searchResultPageHTML(name, serverName, result, ""))
 }
 
-// formatTime 格式化时间
-func formatTime(t time.Time) string {
-	if t.IsZero() {
-		return "-"
+// upstreamError 写入上游调用失败的响应，熔断时返回 503 以便调用方稍后重试
+func upstreamError(c *gin.Context, err error, msg string) {
+	if errors.Is(err, services.ErrCircuitOpen) {
+		c.JSON(http.StatusServiceUnavailable, models.NewError(503, err.Error()))
+		return
+	}
+	c.JSON(http.StatusInternalServerError, models.NewError(500, msg))
+}
+
+// GetProfile 按 UUID 查询用户信息，refresh=1 时跳过缓存
+// @Summary 查询用户信息
+// @Router /llmaget/profile [get]
+func (h *Handler) GetProfile(c *gin.Context) {
+	uuid := c.Query("uuid")
+	if uuid == "" {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "uuid不能为空"))
+		return
+	}
+	if !h.state.HasCookie() {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "请先配置Cookie"))
+		return
 	}
-	return t.Format("2006-01-02 15:04:05")
+
+	data, err := h.ff14Svc.LookupProfile(c.Request.Context(), uuid, c.Query("refresh") == "1")
+	if err != nil {
+		upstreamError(c, err, "查询用户信息失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", data.Data))
+}
+
+// mutatingContext 返回不随客户端断开而取消的上下文，
+// 避免签到、领奖等写操作在请求中途被打断
+func mutatingContext(c *gin.Context) context.Context {
+	return context.WithoutCancel(c.Request.Context())
+}
+
+// formatTime 按业务时区输出 RFC3339 时间，零值返回空字符串
+func formatTime(t time.Time) string {
+	return clock.FormatRFC3339(t)
 }
 
 // HTML 模板
@@ -380,7 +677,7 @@ const configPageHTML = `<!DOCTYPE html>
             <div class="form-group">
                 <label>Cookie (ff14risingstones 的值)</label>
                 <textarea name="cookie" placeholder="粘贴 ff14risingstones cookie 值..."></textarea>
-                <div class="hint">💡 在浏览器登录石之家后，F12 → Application → Cookies → 复制 ff14risingstones 的值</div>
+                <div class="hint">💡 在浏览器登录石之家后，F12 → Application → Cookies → 复制 ff14risingstones 的值，或使用下方的导入</div>
             </div>
             <div class="form-group">
                 <label>User-Agent (可选)</label>
@@ -388,12 +685,63 @@ const configPageHTML = `<!DOCTYPE html>
             </div>
             <button type="submit">💾 保存配置</button>
         </form>
+        <div class="form-group" style="margin-top: 40px;">
+            <label>从浏览器导出导入 Cookie</label>
+            <textarea id="import-data" placeholder="粘贴 Cookie 请求头、cookies.txt、EditThisCookie JSON 或 HAR 内容..."></textarea>
+            <input type="file" id="import-file" accept=".txt,.har,.json" style="margin-top: 12px;">
+            <div class="hint">💡 支持 Netscape cookies.txt、开发者工具 Network 面板导出的 HAR、EditThisCookie / Cookie-Editor 导出的 JSON，或整段 Cookie 请求头，自动提取 ff14risingstones 并检查登录状态</div>
+            <button type="button" onclick="importCookie()">📥 导入并检查</button>
+            <div class="hint" id="import-result"></div>
+        </div>
+        <div class="form-group" style="margin-top: 40px;">
+            <label>一键推送书签</label>
+            <div class="hint">💡 生成后将下方链接拖到书签栏，在已登录的石之家页面点击即可把 Cookie 推送到本服务。书签只能使用一次，有效期见下方提示，过期后重新生成</div>
+            <button type="button" onclick="pushToken()">🔖 生成书签</button>
+            <div class="hint" id="push-result"></div>
+        </div>
+        <script>
+            document.getElementById('import-file').addEventListener('change', function (e) {
+                if (e.target.files.length) {
+                    e.target.files[0].text().then(function (t) { document.getElementById('import-data').value = t; });
+                }
+            });
+            function importCookie() {
+                var out = document.getElementById('import-result');
+                out.textContent = '⏳ 导入中...';
+                fetch('/llmaget/config/import-cookie', { method: 'POST', body: document.getElementById('import-data').value })
+                    .then(function (r) { return r.json(); })
+                    .then(function (r) {
+                        if (r.code !== 10000) { out.textContent = '❌ ' + r.msg; return; }
+                        var exp = r.data.expires ? '，过期时间 ' + new Date(r.data.expires).toLocaleString() : '，未提供过期时间';
+                        out.textContent = (r.data.session.valid ? '✅ ' : '⚠️ ') + r.msg + exp;
+                    })
+                    .catch(function (e) { out.textContent = '❌ ' + e; });
+            }
+            function pushToken() {
+                var out = document.getElementById('push-result');
+                fetch('/llmaget/config/push-token', { method: 'POST' })
+                    .then(function (r) { return r.json(); })
+                    .then(function (r) {
+                        if (r.code !== 10000) { out.textContent = '❌ ' + r.msg; return; }
+                        out.textContent = '';
+                        var a = document.createElement('a');
+                        a.href = r.data.bookmarklet;
+                        a.textContent = '🍪 推送石之家 Cookie';
+                        a.style.cssText = 'display:inline-block;margin:8px 0;padding:8px 16px;border-radius:8px;background:rgba(0,212,255,0.1);color:#00d4ff;text-decoration:none;';
+                        out.appendChild(a);
+                        out.appendChild(document.createElement('br'));
+                        out.appendChild(document.createTextNode('有效期至 ' + new Date(r.data.expires_at).toLocaleString() + '，仅在 ' + r.data.origins.join('、') + ' 上可用'));
+                    })
+                    .catch(function (e) { out.textContent = '❌ ' + e; });
+            }
+        </script>
         <div class="links">
             <a href="/llmaget/search">🔍 搜索用户</a>
             <a href="/llmaget/refresh">🔄 刷新数据</a>
             <a href="/llmaget/status">📊 查看状态</a>
             <a href="/llmaget/ff_info">📄 查看数据</a>
             <a href="/llmaget/sign_in">✍️ 打卡</a>
+            <a href="/llmaget/shop/dashboard">🛒 积分商城</a>
         </div>
     </div>
 </body>
@@ -604,6 +952,7 @@ const searchPageHTML = `<!DOCTYPE html>
             <a href="/llmaget/status">📊 查看状态</a>
             <a href="/llmaget/ff_info">📄 查看数据</a>
             <a href="/llmaget/sign_in">✍️ 打卡</a>
+            <a href="/llmaget/shop/dashboard">🛒 积分商城</a>
         </div>
     </div>
 </body>
@@ -827,6 +1176,7 @@ func searchResultPageHTML(name, serverName string, result *models.UserInfo, erro
             <a href="/llmaget/status">📊 查看状态</a>
             <a href="/llmaget/ff_info">📄 查看数据</a>
             <a href="/llmaget/sign_in">✍️ 打卡</a>
+            <a href="/llmaget/shop/dashboard">🛒 积分商城</a>
         </div>
     </div>
 </body>
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/clock"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/models"
+	"llmaget/recap"
+)
+
+// PreviewRecap 生成周报预览但不发送，默认统计上一个自然周
+// @Summary 预览周报
+// @Router /llmaget/recap [get]
+func (h *Handler) PreviewRecap(c *gin.Context) {
+	from, to := recap.LastWeek(clock.Now())
+	if c.Query("from") != "" || c.Query("to") != "" {
+		f, err := export.ParseFilter(c.Query("from"), c.Query("to"), "")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+			return
+		}
+		if f.From.IsZero() || f.To.IsZero() {
+			c.JSON(http.StatusBadRequest, models.NewError(400, "from 与 to 需同时提供"))
+			return
+		}
+		from, to = f.From, f.To
+	}
+
+	recaps, err := recap.Generate(c.Request.Context(), h.exportSources(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", recap.Result{From: from, To: to, Recaps: recaps}))
+}
+
+// SendRecap 立即生成上一个自然周的周报并通过通知渠道发送
+// @Summary 发送周报
+// @Router /llmaget/recap/send [post]
+func (h *Handler) SendRecap(c *gin.Context) {
+	h.runJob(c, jobs.KindWeeklyRecap, "发送周报过程中发生错误")
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/clock"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/models"
+	"llmaget/shop"
+)
+
+// dashboardHistoryDays 看板积分曲线展示的天数
+const dashboardHistoryDays = 30
+
+// GetShop 获取最近一次积分商城检查的积分与商品状态
+// @Summary 获取积分商城状态
+// @Router /llmaget/shop [get]
+func (h *Handler) GetShop(c *gin.Context) {
+	st, err := shop.Latest(h.ff14Svc.ShopLog())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "读取积分记录失败"))
+		return
+	}
+	if st == nil {
+		c.JSON(http.StatusNotFound, models.NewError(404, "还没有积分商城记录"))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", st))
+}
+
+// CheckShop 立即检查积分商城并发送关注商品的提醒，支持 async=1
+// @Summary 检查积分商城
+// @Router /llmaget/shop/check [post]
+func (h *Handler) CheckShop(c *gin.Context) {
+	h.runJob(c, jobs.KindShopCheck, "检查积分商城过程中发生错误")
+}
+
+// PointsHistory 获取积分余额历史，from、to 的格式与导出相同
+// @Summary 获取积分余额历史
+// @Router /llmaget/points/history [get]
+func (h *Handler) PointsHistory(c *gin.Context) {
+	f, err := export.ParseFilter(c.Query("from"), c.Query("to"), "")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	points, err := shop.History(h.ff14Svc.ShopLog(), f.From, f.To)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewError(500, "读取积分记录失败"))
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", points))
+}
+
+// ShopDashboard 积分与商城看板页面
+// @Summary 积分商城看板
+// @Router /llmaget/shop/dashboard [get]
+func (h *Handler) ShopDashboard(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	log := h.ff14Svc.ShopLog()
+	st, err := shop.Latest(log)
+	if err != nil {
+		c.String(http.StatusInternalServerError, errorPageHTML("读取积分记录失败"))
+		return
+	}
+	history, err := shop.History(log, clock.Now().AddDate(0, 0, -dashboardHistoryDays), time.Time{})
+	if err != nil {
+		c.String(http.StatusInternalServerError, errorPageHTML("读取积分记录失败"))
+		return
+	}
+
+	c.Status(http.StatusOK)
+	if err := shopDashboardTmpl.Execute(c.Writer, newDashboardView(st, history)); err != nil {
+		slog.ErrorContext(c.Request.Context(), "❌ 渲染积分商城看板失败", "error", err)
+	}
+}
+
+// dashboardView 看板页面的数据
+type dashboardView struct {
+	State  *shop.State
+	Change string
+	// Line 积分曲线的 SVG 折线坐标
+	Line  string
+	Min   int
+	Max   int
+	Count int
+	Days  int
+}
+
+// newDashboardView 由最近状态与积分历史生成看板数据
+func newDashboardView(st *shop.State, history []shop.Point) dashboardView {
+	v := dashboardView{State: st, Count: len(history), Days: dashboardHistoryDays}
+	if st != nil && st.Change != nil {
+		v.Change = fmt.Sprintf("%+d", *st.Change)
+	}
+	if len(history) == 0 {
+		return v
+	}
+
+	v.Min, v.Max = history[0].Points, history[0].Points
+	for _, p := range history {
+		v.Min, v.Max = min(v.Min, p.Points), max(v.Max, p.Points)
+	}
+	const width, height = 560.0, 120.0
+	span := float64(max(v.Max-v.Min, 1))
+	for i, p := range history {
+		x := width / 2
+		if len(history) > 1 {
+			x = width * float64(i) / float64(len(history)-1)
+		}
+		y := height - height*float64(p.Points-v.Min)/span
+		v.Line += fmt.Sprintf("%.1f,%.1f ", x, y)
+	}
+	return v
+}
+
+// shopDashboardTmpl 看板页面模板，与配置页同一风格
+var shopDashboardTmpl = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>FF14 石之家 - 积分商城</title>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <style>
+        * { box-sizing: border-box; margin: 0; padding: 0; }
+        body {
+            font-family: 'Segoe UI', -apple-system, BlinkMacSystemFont, sans-serif;
+            background: linear-gradient(135deg, #1a1a2e 0%, #16213e 50%, #0f3460 100%);
+            min-height: 100vh;
+            padding: 40px 20px;
+            color: #e8e8e8;
+        }
+        .container {
+            max-width: 720px;
+            margin: 0 auto;
+            background: rgba(255, 255, 255, 0.05);
+            backdrop-filter: blur(10px);
+            border-radius: 20px;
+            padding: 40px;
+            border: 1px solid rgba(255, 255, 255, 0.1);
+            box-shadow: 0 25px 50px rgba(0, 0, 0, 0.3);
+        }
+        h1 { color: #00d4ff; margin-bottom: 24px; font-size: 28px; }
+        h2 { color: #b8b8b8; margin: 28px 0 12px; font-size: 18px; }
+        .points { font-size: 40px; font-weight: 600; color: #fff; }
+        .change { font-size: 16px; margin-left: 12px; color: #888; }
+        .hint { font-size: 12px; color: #888; margin-top: 8px; line-height: 1.6; }
+        svg { width: 100%; height: 140px; background: rgba(0, 0, 0, 0.3); border-radius: 12px; padding: 10px; }
+        table { width: 100%; border-collapse: collapse; font-size: 14px; }
+        th, td { padding: 10px 8px; text-align: left; border-bottom: 1px solid rgba(255, 255, 255, 0.1); }
+        th { color: #888; font-weight: 500; }
+        tr.watched td { color: #00d4ff; }
+        .ok { color: #4ade80; }
+        .no { color: #888; }
+        .links {
+            margin-top: 30px;
+            padding-top: 20px;
+            border-top: 1px solid rgba(255, 255, 255, 0.1);
+            display: flex;
+            flex-wrap: wrap;
+            gap: 16px;
+        }
+        .links a {
+            color: #00d4ff;
+            text-decoration: none;
+            padding: 8px 16px;
+            border-radius: 8px;
+            background: rgba(0, 212, 255, 0.1);
+        }
+        .links a:hover { background: rgba(0, 212, 255, 0.2); }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1>🛒 积分商城</h1>
+        {{- if .State}}
+        <div><span class="points">{{.State.Points}}</span>{{if .Change}}<span class="change">较上次 {{.Change}}</span>{{end}}</div>
+        <div class="hint">最近检查：{{.State.CheckedAt.Format "2006-01-02 15:04:05"}}</div>
+
+        <h2>📈 近 {{.Days}} 天积分</h2>
+        {{- if .Line}}
+        <svg viewBox="0 0 560 120" preserveAspectRatio="none">
+            <polyline points="{{.Line}}" fill="none" stroke="#00d4ff" stroke-width="2" vector-effect="non-scaling-stroke"/>
+        </svg>
+        <div class="hint">{{.Count}} 次记录，最低 {{.Min}}，最高 {{.Max}}</div>
+        {{- else}}
+        <div class="hint">暂无记录</div>
+        {{- end}}
+
+        <h2>🎁 商品</h2>
+        <table>
+            <tr><th>商品</th><th>积分</th><th>库存</th><th>可兑换</th></tr>
+            {{- range .State.Items}}
+            <tr{{if .Watched}} class="watched"{{end}}>
+                <td>{{if .Watched}}⭐ {{end}}{{.Name}}</td>
+                <td>{{.Cost}}</td>
+                <td>{{.Stock}}</td>
+                <td>{{if .Affordable}}<span class="ok">✅</span>{{else}}<span class="no">—</span>{{end}}</td>
+            </tr>
+            {{- end}}
+        </table>
+        <div class="hint">⭐ 为 shop_watch 中关注的商品，补货或积分足够时通过 shop 事件通知</div>
+        {{- else}}
+        <div class="hint">还没有积分商城记录，等待定时检查或调用 POST /llmaget/shop/check</div>
+        {{- end}}
+        <div class="links">
+            <a href="/llmaget/set">⚙️ 配置设置</a>
+            <a href="/llmaget/search">🔍 搜索用户</a>
+            <a href="/llmaget/status">📊 查看状态</a>
+            <a href="/llmaget/shop">📄 查看数据</a>
+        </div>
+    </div>
+</body>
+</html>`))
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/models"
+	"llmaget/sites"
+)
+
+// ListSites 列出已注册的站点及其任务
+// @Summary 获取站点列表
+// @Router /llmaget/sites [get]
+func (h *Handler) ListSites(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccess("success", h.sites.Status()))
+}
+
+// CheckSiteSession 请求站点确认登录凭据是否有效
+// @Summary 检查站点会话
+// @Router /llmaget/sites/{site}/session [get]
+func (h *Handler) CheckSiteSession(c *gin.Context) {
+	a, ok := h.site(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", h.sites.CheckSession(c.Request.Context(), a)))
+}
+
+// GetSiteInfo 从站点获取账号基础信息
+// @Summary 获取站点账号信息
+// @Router /llmaget/sites/{site}/info [get]
+func (h *Handler) GetSiteInfo(c *gin.Context) {
+	a, ok := h.site(c)
+	if !ok {
+		return
+	}
+	if err := sites.Ready(a); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	info, err := a.FetchInfo(c.Request.Context())
+	if err != nil {
+		upstreamError(c, err, "获取账号信息失败: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", info))
+}
+
+// RunSiteTask 立即执行站点的任务，支持 async=1
+// @Summary 执行站点任务
+// @Router /llmaget/sites/{site}/tasks/{task} [post]
+func (h *Handler) RunSiteTask(c *gin.Context) {
+	a, t, err := h.sites.Task(c.Param("site"), c.Param("task"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.NewError(404, err.Error()))
+		return
+	}
+	if err := sites.Ready(a); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		return
+	}
+	h.runJob(c, t.Kind, t.Title+"过程中发生错误")
+}
+
+// site 读取路径中的站点，不存在时已写入 404 响应
+func (h *Handler) site(c *gin.Context) (sites.Adapter, bool) {
+	a, ok := h.sites.Get(c.Param("site"))
+	if !ok {
+		c.JSON(http.StatusNotFound, models.NewError(404, sites.ErrUnknownSite.Error()+": "+c.Param("site")))
+		return nil, false
+	}
+	return a, true
+}
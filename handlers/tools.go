@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/models"
+	"llmaget/services"
+	"llmaget/tools"
+)
+
+// ListTools 列出代理可调用的工具，格式同 OpenAI Chat Completions 的 tools 参数
+// @Summary 获取函数调用定义
+// @Router /llmaget/tools [get]
+func (h *Handler) ListTools(c *gin.Context) {
+	c.JSON(http.StatusOK, models.NewSuccess("success", h.tools.Functions(h.agentTools())))
+}
+
+// CallTool 执行一次函数调用，参数按工具的 JSON Schema 校验；路由需管理令牌
+// @Summary 执行函数调用
+// @Router /llmaget/tools/call [post]
+func (h *Handler) CallTool(c *gin.Context) {
+	var req models.ToolCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewError(400, "请求格式错误: "+err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	slog.InfoContext(ctx, "🧰 函数调用", "tool", req.Name)
+	result, err := h.tools.CallAllowed(ctx, h.agentTools(), req.Name, req.Arguments)
+	if err != nil {
+		slog.WarnContext(ctx, "⚠️ 函数调用失败", "tool", req.Name, "error", err)
+		switch {
+		case errors.Is(err, tools.ErrUnknownTool):
+			c.JSON(http.StatusNotFound, models.NewError(404, err.Error()))
+		case errors.Is(err, tools.ErrNotAllowed):
+			c.JSON(http.StatusForbidden, models.NewError(403, err.Error()))
+		case errors.Is(err, tools.ErrInvalidArguments):
+			c.JSON(http.StatusBadRequest, models.NewError(400, err.Error()))
+		case errors.Is(err, services.ErrCircuitOpen):
+			c.JSON(http.StatusServiceUnavailable, models.NewError(503, err.Error()))
+		default:
+			c.JSON(http.StatusInternalServerError, models.NewError(500, err.Error()))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, models.NewSuccess("success", gin.H{"name": req.Name, "result": result}))
+}
+
+// agentTools 当前生效的函数调用白名单
+func (h *Handler) agentTools() tools.Allowlist {
+	return tools.Allowlist(h.state.Settings().AgentToolList())
+}
@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"llmaget/clock"
+	"llmaget/logging"
+	"llmaget/store"
+)
+
+// LedgerKinds 记入签到流水的任务类型
+var LedgerKinds = []string{KindSignIn, KindSignAndClaim, KindRewardSweep}
+
+// Ledger 按行追加的签到流水（JSON Lines）
+//
+// 任务历史只保留最近 maxHistory 条，签到相关任务结束后另行追加到这里，只追加不改写。
+type Ledger struct {
+	mu   sync.Mutex
+	file string
+}
+
+// NewLedger 创建签到流水，数据保存在 file 中
+func NewLedger(file string) *Ledger {
+	return &Ledger{file: file}
+}
+
+// Append 追加一条已结束的任务
+func (l *Ledger) Append(job Job) error {
+	line, err := sonic.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("编码签到流水失败: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return store.AppendLine(l.file, line)
+}
+
+// Each 按写入顺序逐条读取流水；无法解析的行会被跳过
+func (l *Ledger) Each(fn func(Job) error) error {
+	f, err := os.Open(l.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var job Job
+		if err := sonic.Unmarshal(scanner.Bytes(), &job); err != nil {
+			slog.Warn("⚠️ 签到流水损坏，跳过", "file", l.file, "line", lineNo, "error", err)
+			continue
+		}
+		if err := fn(job); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Run 不经过任务管理器直接执行签到任务，结束后追加流水
+//
+// 供 llmaget sign、MCP stdio 等不运行任务管理器的入口使用，流水与服务端任务格式一致。
+func (l *Ledger) Run(ctx context.Context, kind, account, trigger string, fn Func) (any, error) {
+	cid := logging.CorrelationID(ctx)
+	if cid == "" {
+		cid = logging.NewCorrelationID()
+		ctx = logging.WithCorrelationID(ctx, cid)
+	}
+	started := clock.Now()
+	job := Job{
+		ID:            uuid.New().String(),
+		Kind:          kind,
+		Account:       account,
+		Trigger:       trigger,
+		CorrelationID: cid,
+		CreatedAt:     started,
+		StartedAt:     &started,
+	}
+
+	result, err := fn(ctx)
+
+	finished := clock.Now()
+	job.FinishedAt = &finished
+	job.Result = result
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusSucceeded
+	}
+	if lerr := l.Append(job); lerr != nil {
+		slog.WarnContext(ctx, "⚠️ 记录签到流水失败", "job", job.ID, "error", lerr)
+	}
+	return result, err
+}
+
+// SetLedger 设置签到流水，LedgerKinds 中的任务结束后追加记录
+//
+// 流水文件不存在时（首次启用）会先补录任务历史中已结束的签到任务。
+func (m *Manager) SetLedger(l *Ledger) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ledger = l
+
+	if _, err := os.Stat(l.file); !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	list := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if job.Finished() && slices.Contains(LedgerKinds, job.Kind) {
+			list = append(list, m.snapshot(job))
+		}
+	}
+	slices.SortFunc(list, func(a, b Job) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, job := range list {
+		if err := l.Append(job); err != nil {
+			slog.Warn("⚠️ 补录签到流水失败", "file", l.file, "error", err)
+			return
+		}
+	}
+	if len(list) > 0 {
+		slog.Info("📒 已从任务历史补录签到流水", "count", len(list))
+	}
+}
+
+// Ledger 获取签到流水，未设置时为 nil
+func (m *Manager) Ledger() *Ledger {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ledger
+}
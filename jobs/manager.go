@@ -0,0 +1,483 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"llmaget/clock"
+	"llmaget/logging"
+	"llmaget/store"
+)
+
+// Status 任务状态
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// 任务类型
+const (
+	KindRefresh      = "refresh"
+	KindSignIn       = "sign_in"
+	KindSignAndClaim = "sign_and_claim"
+	KindRewardSweep  = "reward_sweep"
+	KindMissions     = "missions"
+	KindShopCheck    = "shop_check"
+	KindWeeklyRecap  = "weekly_recap"
+)
+
+// 任务触发来源
+const (
+	TriggerManual    = "manual"
+	TriggerScheduled = "scheduled"
+	TriggerBot       = "bot"
+	TriggerAgent     = "agent"
+)
+
+var (
+	// ErrUnknownKind 未注册的任务类型
+	ErrUnknownKind = errors.New("未知的任务类型")
+	// ErrQueueFull 任务队列已满
+	ErrQueueFull = errors.New("任务队列已满")
+	// ErrShuttingDown 任务管理器正在关闭
+	ErrShuttingDown = errors.New("任务管理器正在关闭")
+)
+
+// Func 任务执行函数
+type Func func(ctx context.Context) (any, error)
+
+// Job 任务记录
+type Job struct {
+	ID            string     `json:"id"`
+	Kind          string     `json:"kind"`
+	Account       string     `json:"account"`
+	Trigger       string     `json:"trigger"`
+	CorrelationID string     `json:"correlation_id"`
+	Status        Status     `json:"status"`
+	CreatedAt     time.Time  `json:"created_at"`
+	StartedAt     *time.Time `json:"started_at,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	Result        any        `json:"result,omitempty"`
+	Error         string     `json:"error,omitempty"`
+
+	done chan struct{}
+}
+
+// Finished 任务是否已结束
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Manager 任务管理器
+//
+// 任务进入内存队列后由固定数量的 worker 执行，同一账号下同类任务
+// 在执行结束前只会存在一个，重复提交会直接返回已有任务。
+type Manager struct {
+	mu         sync.Mutex
+	funcs      map[string]Func
+	jobs       map[string]*Job
+	active     map[string]string
+	queue      chan *Job
+	closed     bool
+	file       string
+	maxHistory int
+	// ledger 签到流水，签到相关任务结束后追加
+	ledger *Ledger
+
+	// dirty 任务状态有变化，由 persistLoop 合并后写入文件
+	dirty       chan struct{}
+	stopPersist chan struct{}
+	persistDone chan struct{}
+
+	wg         sync.WaitGroup
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+}
+
+// NewManager 创建任务管理器，file 为任务历史持久化文件
+func NewManager(file string) *Manager {
+	workCtx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		funcs:      make(map[string]Func),
+		jobs:       make(map[string]*Job),
+		active:     make(map[string]string),
+		queue:      make(chan *Job, 64),
+		file:       file,
+		maxHistory: 200,
+		workCtx:    workCtx,
+		cancelWork: cancel,
+
+		dirty:       make(chan struct{}, 1),
+		stopPersist: make(chan struct{}),
+		persistDone: make(chan struct{}),
+	}
+	m.load()
+	go m.persistLoop()
+	return m
+}
+
+// Register 注册任务类型
+func (m *Manager) Register(kind string, fn Func) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.funcs[kind] = fn
+}
+
+// Start 启动 worker
+func (m *Manager) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			for job := range m.queue {
+				m.execute(job)
+			}
+		}()
+	}
+}
+
+// Submit 提交任务，返回任务快照以及是否与进行中的任务合并
+func (m *Manager) Submit(ctx context.Context, kind, account, trigger string) (Job, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return Job{}, false, ErrShuttingDown
+	}
+	if _, ok := m.funcs[kind]; !ok {
+		return Job{}, false, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	key := activeKey(kind, account)
+	if id, ok := m.active[key]; ok {
+		slog.InfoContext(ctx, "任务已在进行中，合并请求", "job", id, "kind", kind, "account", account)
+		return m.snapshot(m.jobs[id]), true, nil
+	}
+
+	cid := logging.CorrelationID(ctx)
+	if cid == "" {
+		cid = logging.NewCorrelationID()
+	}
+
+	job := &Job{
+		ID:            uuid.New().String(),
+		Kind:          kind,
+		Account:       account,
+		Trigger:       trigger,
+		CorrelationID: cid,
+		Status:        StatusPending,
+		CreatedAt:     clock.Now(),
+		done:          make(chan struct{}),
+	}
+
+	select {
+	case m.queue <- job:
+	default:
+		return Job{}, false, ErrQueueFull
+	}
+
+	m.jobs[job.ID] = job
+	m.active[key] = job.ID
+	m.pruneLocked()
+	m.markDirtyLocked()
+
+	slog.InfoContext(ctx, "📋 任务已提交", "job", job.ID, "kind", kind, "account", account, "trigger", trigger)
+	return m.snapshot(job), false, nil
+}
+
+// Get 获取任务快照
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return m.snapshot(job), true
+}
+
+// List 按创建时间倒序返回最近的任务
+func (m *Manager) List(limit int) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		list = append(list, m.snapshot(job))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.After(list[j].CreatedAt)
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list
+}
+
+// Wait 等待任务结束或 ctx 取消
+func (m *Manager) Wait(ctx context.Context, id string) (Job, error) {
+	m.mu.Lock()
+	job, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("任务不存在: %s", id)
+	}
+
+	if job.done != nil {
+		select {
+		case <-job.done:
+		case <-ctx.Done():
+			return Job{}, ctx.Err()
+		}
+	}
+
+	snapshot, _ := m.Get(id)
+	return snapshot, nil
+}
+
+// Shutdown 停止接收新任务并等待队列执行完毕，超过 ctx 期限后取消进行中的任务
+func (m *Manager) Shutdown(ctx context.Context) {
+	m.mu.Lock()
+	if !m.closed {
+		m.closed = true
+		close(m.queue)
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("✅ 任务队列已清空")
+	case <-ctx.Done():
+		slog.Warn("⚠️ 宽限期已到，取消进行中的任务")
+		m.cancelWork()
+		<-done
+	}
+	m.cancelWork()
+
+	close(m.stopPersist)
+	<-m.persistDone
+	m.flush()
+}
+
+// execute 执行单个任务
+func (m *Manager) execute(job *Job) {
+	ctx := logging.WithCorrelationID(m.workCtx, job.CorrelationID)
+
+	m.mu.Lock()
+	fn := m.funcs[job.Kind]
+	now := clock.Now()
+	job.Status = StatusRunning
+	job.StartedAt = &now
+	m.markDirtyLocked()
+	m.mu.Unlock()
+
+	slog.InfoContext(ctx, "▶️ 任务开始", "job", job.ID, "kind", job.Kind)
+
+	var (
+		result any
+		err    error
+	)
+	if ctx.Err() != nil {
+		err = ErrShuttingDown
+	} else {
+		result, err = fn(ctx)
+	}
+
+	m.mu.Lock()
+	finished := clock.Now()
+	job.FinishedAt = &finished
+	job.Result = result
+	if err != nil {
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	} else {
+		job.Status = StatusSucceeded
+	}
+	delete(m.active, activeKey(job.Kind, job.Account))
+	close(job.done)
+	m.markDirtyLocked()
+	ledger := m.ledger
+	done := m.snapshot(job)
+	m.mu.Unlock()
+
+	if ledger != nil && slices.Contains(LedgerKinds, job.Kind) {
+		if lerr := ledger.Append(done); lerr != nil {
+			slog.WarnContext(ctx, "⚠️ 记录签到流水失败", "job", job.ID, "error", lerr)
+		}
+	}
+
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 任务失败", "job", job.ID, "kind", job.Kind, "error", err, "elapsed", finished.Sub(now))
+		return
+	}
+	slog.InfoContext(ctx, "✅ 任务完成", "job", job.ID, "kind", job.Kind, "elapsed", finished.Sub(now))
+}
+
+// snapshot 复制任务信息（调用方需持有锁）
+func (m *Manager) snapshot(job *Job) Job {
+	cp := *job
+	cp.done = nil
+	return cp
+}
+
+// pruneLocked 只保留最近 maxHistory 条已结束的任务
+func (m *Manager) pruneLocked() {
+	if len(m.jobs) <= m.maxHistory {
+		return
+	}
+
+	finished := make([]*Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		if job.Finished() {
+			finished = append(finished, job)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for i := 0; i < len(finished) && len(m.jobs) > m.maxHistory; i++ {
+		delete(m.jobs, finished[i].ID)
+	}
+}
+
+// persistDelay 任务状态变化后延迟写入，合并短时间内的多次变化
+const persistDelay = 500 * time.Millisecond
+
+// markDirtyLocked 标记任务历史需要写入（调用方需持有锁），不会阻塞
+func (m *Manager) markDirtyLocked() {
+	select {
+	case m.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// persistLoop 合并任务状态变化并在锁外写入文件，直到 Shutdown
+func (m *Manager) persistLoop() {
+	defer close(m.persistDone)
+	for {
+		select {
+		case <-m.dirty:
+		case <-m.stopPersist:
+			return
+		}
+		select {
+		case <-time.After(persistDelay):
+		case <-m.stopPersist:
+		}
+		m.flush()
+	}
+}
+
+// flush 在锁内复制任务快照，在锁外编码并写入文件
+//
+// 只由 persistLoop 与其退出后的 Shutdown 调用，不会并发写入。
+// 任务历史变化频繁，写入时不轮转 .bak 备份，损坏时仍会尝试读取已有的备份。
+func (m *Manager) flush() {
+	m.mu.Lock()
+	list := make([]Job, 0, len(m.jobs))
+	for _, job := range m.jobs {
+		list = append(list, m.snapshot(job))
+	}
+	m.mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	data, err := sonic.MarshalIndent(list, "", "  ")
+	if err != nil {
+		slog.Error("❌ 编码任务历史失败", "error", err)
+		return
+	}
+	if err := store.WriteFile(m.file, data, 0644, 0); err != nil {
+		slog.Error("❌ 保存任务历史失败", "file", m.file, "error", err)
+	}
+}
+
+// LoadHistory 读取任务历史文件，文件不存在时返回 os.ErrNotExist
+func LoadHistory(file string) ([]Job, error) {
+	var list []Job
+	_, err := store.ReadFile(file, func(data []byte) error {
+		list = nil
+		return sonic.Unmarshal(data, &list)
+	}, store.DefaultBackups)
+	if err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// load 从文件恢复任务历史，上次未结束的任务标记为失败
+func (m *Manager) load() {
+	list, err := LoadHistory(m.file)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("⚠️ 任务历史解析失败，忽略", "file", m.file, "error", err)
+		}
+		return
+	}
+
+	for i := range list {
+		job := list[i]
+		if !job.Finished() {
+			job.Status = StatusFailed
+			job.Error = "服务重启，任务中断"
+		}
+		m.jobs[job.ID] = &job
+	}
+	slog.Info("✅ 任务历史加载成功", "count", len(list))
+}
+
+// Reload 用任务历史文件替换内存中已结束的任务，进行中的任务保留，用于从备份恢复后同步
+func (m *Manager) Reload() error {
+	list, err := LoadHistory(m.file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, job := range m.jobs {
+		if job.Finished() {
+			delete(m.jobs, id)
+		}
+	}
+	for i := range list {
+		job := list[i]
+		if _, running := m.jobs[job.ID]; running {
+			continue
+		}
+		if !job.Finished() {
+			job.Status = StatusFailed
+			job.Error = "备份时任务尚未结束"
+		}
+		m.jobs[job.ID] = &job
+	}
+	m.pruneLocked()
+	m.markDirtyLocked()
+	slog.Info("🔄 任务历史已重新加载", "count", len(list))
+	return nil
+}
+
+func activeKey(kind, account string) string {
+	return kind + "/" + account
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EnvLogFormat = "LLMAGET_LOG_FORMAT"
+	EnvLogLevel  = "LLMAGET_LOG_LEVEL"
+)
+
+type ctxKey struct{}
+
+// Setup 初始化全局 slog 日志，format 为 text 或 json
+func Setup(format, level string) {
+	opts := &slog.HandlerOptions{Level: ParseLevel(level)}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "json") {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	}
+
+	slog.SetDefault(slog.New(&contextHandler{Handler: handler}))
+}
+
+// SetupFromEnv 根据环境变量初始化日志
+func SetupFromEnv() {
+	Setup(os.Getenv(EnvLogFormat), os.Getenv(EnvLogLevel))
+}
+
+// ParseLevel 解析日志级别，无法识别时返回 info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// NewCorrelationID 生成新的关联 ID
+func NewCorrelationID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "")[:16]
+}
+
+// WithCorrelationID 将关联 ID 写入上下文
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// NewContext 创建携带新关联 ID 的上下文
+func NewContext(parent context.Context) context.Context {
+	return WithCorrelationID(parent, NewCorrelationID())
+}
+
+// CorrelationID 从上下文读取关联 ID
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// contextHandler 自动为日志附加上下文中的关联 ID
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		r.AddAttrs(slog.String("cid", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
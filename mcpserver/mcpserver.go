@@ -0,0 +1,126 @@
+package mcpserver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"llmaget/logging"
+	"llmaget/services"
+	"llmaget/tools"
+)
+
+// implementation MCP 服务端标识
+var implementation = &mcp.Implementation{Name: "llmaget", Title: "石之家助手", Version: "1.0.0"}
+
+// instructions 提示客户端如何使用工具
+const instructions = "石之家（FF14 社区）账号工具。查询类工具只读；sign_in_and_claim 在开放时会为当前账号签到并领取奖励。"
+
+// Allow 返回当前的工具白名单（settings.agent_tools），与函数调用接口共用
+type Allow func() tools.Allowlist
+
+// New 基于工具注册表创建 MCP 服务端，只列出白名单允许的工具
+func New(reg *tools.Registry, allow Allow) *mcp.Server {
+	server := mcp.NewServer(implementation, &mcp.ServerOptions{Instructions: instructions})
+	list := allow()
+	for _, t := range reg.List() {
+		if !list.Allows(t) {
+			continue
+		}
+		server.AddTool(&mcp.Tool{
+			Name:        t.Name,
+			Title:       t.Title,
+			Description: t.Description,
+			InputSchema: t.InputSchema,
+			Annotations: annotations(t),
+		}, handler(reg, allow, t.Name))
+	}
+	return server
+}
+
+// annotations 将工具的只读 / 幂等属性转换为 MCP 提示
+func annotations(t tools.Tool) *mcp.ToolAnnotations {
+	destructive, openWorld := false, true
+	return &mcp.ToolAnnotations{
+		Title:           t.Title,
+		ReadOnlyHint:    t.ReadOnly,
+		IdempotentHint:  t.Idempotent,
+		DestructiveHint: &destructive,
+		OpenWorldHint:   &openWorld,
+	}
+}
+
+// handler 调用注册表中的工具，业务错误以 isError 结果返回给模型
+//
+// 调用时重新校验白名单，agent_tools 热加载后收回的工具在已建立的会话中同样不可调用。
+func handler(reg *tools.Registry, allow Allow, name string) mcp.ToolHandler {
+	return func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ctx = logging.WithCorrelationID(ctx, logging.NewCorrelationID())
+		slog.InfoContext(ctx, "🧰 MCP 工具调用", "tool", name)
+
+		out, err := reg.CallAllowed(ctx, allow(), name, req.Params.Arguments)
+		if err != nil {
+			slog.WarnContext(ctx, "⚠️ MCP 工具调用失败", "tool", name, "error", err)
+			msg := err.Error()
+			if errors.Is(err, services.ErrCircuitOpen) {
+				msg = "石之家暂时无法访问，请稍后再试"
+			}
+			return &mcp.CallToolResult{IsError: true, Content: []mcp.Content{&mcp.TextContent{Text: msg}}}, nil
+		}
+
+		data, err := sonic.Marshal(out)
+		if err != nil {
+			return nil, err
+		}
+		var structured any
+		if err := sonic.Unmarshal(data, &structured); err != nil {
+			return nil, err
+		}
+		res := &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: string(data)}}}
+		// structuredContent 必须是对象
+		if _, ok := structured.(map[string]any); ok {
+			res.StructuredContent = structured
+		}
+		return res, nil
+	}
+}
+
+// RunStdio 通过标准输入输出提供 MCP 服务，直到客户端断开或 ctx 取消
+func RunStdio(ctx context.Context, reg *tools.Registry, allow Allow) error {
+	err := New(reg, allow).Run(ctx, &mcp.StdioTransport{})
+	// 客户端关闭标准输入是正常退出
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// HTTPHandler 创建 Streamable HTTP 传输的处理器
+//
+// 白名单不变时所有会话共用同一个服务端；agent_tools 变化后新会话使用按新白名单创建的服务端。
+func HTTPHandler(reg *tools.Registry, allow Allow) http.Handler {
+	var (
+		mu     sync.Mutex
+		key    string
+		server *mcp.Server
+	)
+	getServer := func(*http.Request) *mcp.Server {
+		k := strings.Join(allow(), ",")
+		mu.Lock()
+		defer mu.Unlock()
+		if server == nil || k != key {
+			server, key = New(reg, allow), k
+		}
+		return server
+	}
+	return mcp.NewStreamableHTTPHandler(getServer, &mcp.StreamableHTTPOptions{
+		Logger: slog.Default(),
+	})
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "llmaget"
+
+var (
+	// UpstreamRequests 上游请求计数，按路径、HTTP 状态码和业务错误码区分
+	UpstreamRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "石之家上游请求次数",
+	}, []string{"path", "method", "status", "code"})
+
+	// UpstreamDuration 上游请求耗时
+	UpstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "石之家上游请求耗时（秒）",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"path", "method"})
+
+	// UpstreamThrottled 上游限流次数，按主机区分
+	UpstreamThrottled = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_throttled_total",
+		Help:      "石之家上游返回限流（429 或限流业务码）的次数",
+	}, []string{"host"})
+
+	// UpstreamLimiterWait 本地限速器的等待时长
+	UpstreamLimiterWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_limiter_wait_seconds",
+		Help:      "请求发出前在本地限速器上等待的时长（秒）",
+		Buckets:   []float64{0, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	// CircuitState 上游熔断器状态（0 关闭，1 半开，2 打开）
+	CircuitState = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "upstream_circuit_state",
+		Help:      "石之家上游熔断器状态（0 关闭，1 半开，2 打开）",
+	})
+
+	// SignIns 签到结果计数
+	SignIns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sign_in_total",
+		Help:      "签到次数",
+	}, []string{"result"})
+
+	// RewardClaims 签到奖励领取计数
+	RewardClaims = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reward_claims_total",
+		Help:      "签到奖励领取次数",
+	}, []string{"result"})
+
+	// SessionValid Cookie 会话是否有效（1 有效，0 无效）
+	SessionValid = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "session_valid",
+		Help:      "石之家会话是否有效",
+	})
+
+	// LastFetchSuccess 最后一次成功获取基础信息的时间戳
+	LastFetchSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_fetch_success_timestamp_seconds",
+		Help:      "最后一次成功获取基础信息的 Unix 时间戳",
+	})
+
+	// LastSignSuccess 最后一次成功签到的时间戳
+	LastSignSuccess = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_sign_success_timestamp_seconds",
+		Help:      "最后一次成功签到的 Unix 时间戳",
+	})
+
+	// BotCommands 机器人命令计数，result 为 ok、error、denied 或 cooldown
+	BotCommands = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bot_commands_total",
+		Help:      "QQ 群机器人命令次数",
+	}, []string{"command", "result"})
+
+	// Notifications 通知发送计数，result 为 ok 或 error
+	Notifications = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notifications_total",
+		Help:      "通知发送次数",
+	}, []string{"channel", "event", "result"})
+
+	// PlayTimeMinutes 角色游戏时长（分钟）
+	PlayTimeMinutes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "play_time_minutes",
+		Help:      "角色游戏时长（分钟）",
+	}, []string{"character", "group"})
+
+	// PointsBalance 社区积分余额
+	PointsBalance = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "points_balance",
+		Help:      "社区积分余额",
+	})
+)
+
+// ObserveUpstream 记录一次上游请求
+func ObserveUpstream(path, method string, status int, code string, elapsed time.Duration) {
+	statusLabel := "error"
+	if status > 0 {
+		statusLabel = strconv.Itoa(status)
+	}
+	UpstreamRequests.WithLabelValues(path, method, statusLabel, code).Inc()
+	UpstreamDuration.WithLabelValues(path, method).Observe(elapsed.Seconds())
+}
+
+// ObserveResult 记录一次成功或失败
+func ObserveResult(vec *prometheus.CounterVec, ok bool) {
+	if ok {
+		vec.WithLabelValues("success").Inc()
+		return
+	}
+	vec.WithLabelValues("failure").Inc()
+}
+
+// SetSessionValid 设置会话有效状态
+func SetSessionValid(valid bool) {
+	if valid {
+		SessionValid.Set(1)
+		return
+	}
+	SessionValid.Set(0)
+}
+
+// MarkNow 将时间戳指标设置为当前时间
+func MarkNow(g prometheus.Gauge) {
+	g.Set(float64(time.Now().Unix()))
+}
@@ -1,7 +1,12 @@
 package models
 
 import (
+	"encoding/json"
+	"time"
+
 	"github.com/bytedance/sonic"
+
+	"llmaget/sites"
 )
 
 // APIResponse FF14 API 原始响应结构
@@ -131,21 +136,130 @@ type UserInfoResp struct {
 }
 
 type SignInRewards struct {
+	Code int          `json:"code"`
+	Msg  string       `json:"msg"`
+	Data []SignReward `json:"data"`
+}
+
+// SignReward 签到奖励
+type SignReward struct {
+	ID        int    `json:"id"`
+	BeginDate string `json:"begin_date"`
+	EndDate   string `json:"end_date"`
+	Rule      int    `json:"rule"`
+	ItemName  string `json:"item_name"`
+	ItemPic   string `json:"item_pic"`
+	Num       int    `json:"num"`
+	ItemDesc  string `json:"item_desc"`
+	IsGet     int    `json:"is_get"`
+}
+
+// 社区任务状态
+const (
+	MissionIncomplete = 0 // 未完成
+	MissionCompleted  = 1 // 已完成，积分待领取
+	MissionClaimed    = 2 // 积分已领取
+)
+
+// MissionList 每日社区任务列表
+type MissionList struct {
+	Code int       `json:"code"`
+	Msg  string    `json:"msg"`
+	Data []Mission `json:"data"`
+}
+
+// Mission 社区任务，Type 为 browse、like、comment 等动作类型
+type Mission struct {
+	ID        int    `json:"id"`
+	Name      string `json:"task_name"`
+	Type      string `json:"task_type"`
+	TargetNum int    `json:"target_num"`
+	FinishNum int    `json:"finish_num"`
+	Points    int    `json:"points"`
+	Status    int    `json:"status"`
+}
+
+// Remaining 距离完成还需执行的次数
+func (m Mission) Remaining() int {
+	return max(0, m.TargetNum-m.FinishNum)
+}
+
+// PostsList 帖子列表
+type PostsList struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+	Data struct {
+		Rows []Post `json:"rows"`
+	} `json:"data"`
+}
+
+// Post 帖子
+type Post struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	IsLike int    `json:"is_like"`
+}
+
+// PointsResp 社区积分余额
+type PointsResp struct {
 	Code int    `json:"code"`
 	Msg  string `json:"msg"`
-	Data []struct {
-		ID        int    `json:"id"`
-		BeginDate string `json:"begin_date"`
-		EndDate   string `json:"end_date"`
-		Rule      int    `json:"rule"`
-		ItemName  string `json:"item_name"`
-		ItemPic   string `json:"item_pic"`
-		Num       int    `json:"num"`
-		ItemDesc  string `json:"item_desc"`
-		IsGet     int    `json:"is_get"`
+	Data struct {
+		Points int `json:"integral"`
 	} `json:"data"`
 }
 
+// ShopItems 积分商城商品列表
+type ShopItems struct {
+	Code int        `json:"code"`
+	Msg  string     `json:"msg"`
+	Data []ShopItem `json:"data"`
+}
+
+// ShopItem 积分商城商品，Cost 为兑换所需积分
+type ShopItem struct {
+	ID    int    `json:"id"`
+	Name  string `json:"goods_name"`
+	Cost  int    `json:"integral"`
+	Stock int    `json:"stock"`
+}
+
+// ShopRecord 一次积分与商城库存检查的记录
+type ShopRecord struct {
+	Time    time.Time  `json:"time"`
+	Account string     `json:"account"`
+	Points  int        `json:"points"`
+	Items   []ShopItem `json:"items"`
+}
+
+// RewardRecord 奖励历史中的单条奖励，ClaimedAt 为本服务领取成功的时间
+type RewardRecord struct {
+	SignReward
+	ClaimedAt *time.Time `json:"claimed_at,omitempty"`
+}
+
+// RewardMonth 单月签到奖励表
+type RewardMonth struct {
+	Month     string         `json:"month"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Rewards   []RewardRecord `json:"rewards"`
+}
+
+// Snapshot 角色基础信息快照，每次刷新追加一条
+type Snapshot struct {
+	Time            time.Time         `json:"time"`
+	Account         string            `json:"account"`
+	UUID            string            `json:"uuid"`
+	CharacterName   string            `json:"character_name"`
+	AreaName        string            `json:"area_name"`
+	GroupName       string            `json:"group_name"`
+	PlayTime        string            `json:"play_time"`
+	PlayTimeMinutes int               `json:"play_time_minutes"`
+	JobLevels       map[string]string `json:"job_levels,omitempty"`
+	// Achievements 石之家主页展示的近期成就名称
+	Achievements []string `json:"achievements,omitempty"`
+}
+
 // Response 统一响应结构
 type Response struct {
 	Code int    `json:"code"`
@@ -181,11 +295,37 @@ type UserProfile struct {
 
 // StatusData 状态数据
 type StatusData struct {
-	HasData       bool   `json:"has_data"`
-	HasCookie     bool   `json:"has_cookie"`
-	LastFetchAt   string `json:"last_fetch_at"`
-	NextFetchAt   string `json:"next_fetch_at"`
-	FetchInterval string `json:"fetch_interval"`
+	HasData       bool          `json:"has_data"`
+	HasCookie     bool          `json:"has_cookie"`
+	LastFetchAt   string        `json:"last_fetch_at"`
+	NextFetchAt   string        `json:"next_fetch_at"`
+	FetchInterval string        `json:"fetch_interval"`
+	Breaker       BreakerStatus `json:"breaker"`
+	Cache         CacheStats    `json:"cache"`
+	// Sites 各站点的启用、凭据与最近一次会话检查情况
+	Sites []sites.Status `json:"sites"`
+}
+
+// CacheStats 查询缓存统计，Shared 为参与并发合并（共享同一次加载结果）的调用次数
+type CacheStats struct {
+	Entries int   `json:"entries"`
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Shared  int64 `json:"shared"`
+}
+
+// BreakerStatus 上游熔断器状态
+type BreakerStatus struct {
+	State    string `json:"state"`
+	Failures int    `json:"failures"`
+	OpenedAt string `json:"opened_at,omitempty"`
+	RetryAt  string `json:"retry_at,omitempty"`
+}
+
+// JobSubmitData 任务提交响应数据
+type JobSubmitData struct {
+	JobID   string `json:"job_id"`
+	Deduped bool   `json:"deduped"`
 }
 
 // ConfigRequest 配置请求
@@ -194,6 +334,25 @@ type ConfigRequest struct {
 	Cookie    string `json:"cookie"`
 }
 
+// CookiePushRequest 书签推送 Cookie 的请求，Cookie 为页面的 document.cookie
+type CookiePushRequest struct {
+	Token  string `json:"token"`
+	Cookie string `json:"cookie"`
+}
+
+// CookiePushToken 书签推送令牌，Bookmarklet 为可拖到书签栏的 javascript: 地址
+type CookiePushToken struct {
+	Bookmarklet string    `json:"bookmarklet"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	Origins     []string  `json:"origins"`
+}
+
+// ToolCallRequest 函数调用请求，Arguments 可为 JSON 对象或 JSON 字符串
+type ToolCallRequest struct {
+	Name      string          `json:"name" binding:"required"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
 // ConfigData 配置响应数据
 type ConfigData struct {
 	HasCookie bool `json:"has_cookie"`
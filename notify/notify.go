@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"llmaget/config"
+	"llmaget/metrics"
+)
+
+// 通知事件
+const (
+	EventWeeklyRecap = "weekly_recap"
+	EventShop        = "shop"
+)
+
+// Message 一条通知
+type Message struct {
+	Event string `json:"event"`
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+// client 通知渠道共用的 HTTP 客户端
+var client = resty.New().SetTimeout(15 * time.Second)
+
+// Send 将消息发送到所有订阅了该事件的渠道，返回成功的渠道数；部分渠道失败时返回合并的错误
+func Send(ctx context.Context, msg Message) (int, error) {
+	cfg := config.GetState().Notify()
+	if cfg == nil {
+		return 0, nil
+	}
+
+	sent := 0
+	var errs []error
+	for _, ch := range cfg.Channels {
+		if !ch.Wants(msg.Event) {
+			continue
+		}
+		if err := send(ctx, ch, msg); err != nil {
+			metrics.Notifications.WithLabelValues(ch.Name, msg.Event, "error").Inc()
+			slog.WarnContext(ctx, "⚠️ 通知发送失败", "channel", ch.Name, "event", msg.Event, "error", err)
+			errs = append(errs, fmt.Errorf("%s: %w", ch.Name, err))
+			continue
+		}
+		metrics.Notifications.WithLabelValues(ch.Name, msg.Event, "ok").Inc()
+		slog.InfoContext(ctx, "📨 通知已发送", "channel", ch.Name, "event", msg.Event)
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
+
+// send 按渠道类型组织请求
+func send(ctx context.Context, ch config.NotifyChannel, msg Message) error {
+	req := client.R().SetContext(ctx)
+	switch ch.Type {
+	case config.NotifyWeCom:
+		req.SetBody(map[string]any{
+			"msgtype": "text",
+			"text":    map[string]string{"content": msg.Title + "\n\n" + msg.Text},
+		})
+	case config.NotifyBark:
+		req.SetBody(map[string]string{"title": msg.Title, "body": msg.Text, "group": "llmaget"})
+	case config.NotifyServerChan:
+		// Server 酱的 desp 为 Markdown，换行需要空行
+		req.SetFormData(map[string]string{"title": msg.Title, "desp": strings.ReplaceAll(msg.Text, "\n", "\n\n")})
+	default:
+		req.SetBody(msg)
+	}
+
+	resp, err := req.Post(ch.URL)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode(), truncate(resp.String(), 200))
+	}
+	return checkBody(ch.Type, resp.Body())
+}
+
+// checkBody 检查渠道返回的业务错误码
+func checkBody(typ string, body []byte) error {
+	var r struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Message string `json:"message"`
+	}
+	if client.JSONUnmarshal(body, &r) != nil {
+		return nil
+	}
+	switch typ {
+	case config.NotifyWeCom:
+		if r.ErrCode != nil && *r.ErrCode != 0 {
+			return fmt.Errorf("企业微信错误 %d: %s", *r.ErrCode, r.ErrMsg)
+		}
+	case config.NotifyBark:
+		if r.Code != nil && *r.Code != 200 {
+			return fmt.Errorf("Bark 错误 %d: %s", *r.Code, r.Message)
+		}
+	case config.NotifyServerChan:
+		if r.Code != nil && *r.Code != 0 {
+			return fmt.Errorf("Server 酱错误 %d: %s", *r.Code, r.Message)
+		}
+	}
+	return nil
+}
+
+// truncate 截断过长的响应内容
+func truncate(s string, n int) string {
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "..."
+}
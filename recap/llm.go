@@ -0,0 +1,107 @@
+package recap
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-resty/resty/v2"
+
+	"llmaget/config"
+)
+
+// systemPrompt 约束模型只复述数据中的事实
+const systemPrompt = `你是《最终幻想14》玩家的周报助手。根据用户提供的 JSON 数据，用简体中文为该角色写一段简短、友好的周报，不超过 200 字。
+只能使用数据中的事实，不要编造数字、职业或成就；数据为空的项目可以略过。play_time_minutes 与 play_time_delta 的单位为分钟。
+直接输出周报正文，不要使用 Markdown 标题，不要解释你的写作过程。`
+
+// thinkBlock 部分推理模型（如 Ollama 上的 qwen3、deepseek-r1）会在正文前输出思考过程
+var thinkBlock = regexp.MustCompile(`(?s)<think>.*?</think>`)
+
+// chatMessage Chat Completions 消息
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest Chat Completions 请求
+type chatRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	Stream      bool          `json:"stream"`
+}
+
+// chatResponse Chat Completions 响应，只解析需要的字段
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// write 生成单个角色的周报，返回正文与来源
+func write(ctx context.Context, st config.Settings, s Summary) (string, string) {
+	fallback := Render(s)
+	if st.LLMBaseURL == "" {
+		return fallback, SourceTemplate
+	}
+	text, err := complete(ctx, st, s, fallback)
+	if err != nil {
+		slog.WarnContext(ctx, "⚠️ LLM 生成周报失败，改用模板", "model", st.LLMModel, "error", err)
+		return fallback, SourceTemplate
+	}
+	return text, SourceLLM
+}
+
+// complete 调用 OpenAI 兼容的 /chat/completions 接口
+func complete(ctx context.Context, st config.Settings, s Summary, fallback string) (string, error) {
+	data, err := sonic.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	body := chatRequest{
+		Model: st.LLMModel,
+		Messages: []chatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: "数据：\n" + string(data) + "\n\n可参考的模板输出：\n" + fallback},
+		},
+		Temperature: 0.7,
+	}
+
+	req := resty.New().SetTimeout(st.LLMTimeout.D()).R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body)
+	if st.LLMAPIKey != "" {
+		req.SetAuthToken(st.LLMAPIKey)
+	}
+	resp, err := req.Post(strings.TrimRight(st.LLMBaseURL, "/") + "/chat/completions")
+	if err != nil {
+		return "", err
+	}
+
+	var out chatResponse
+	if err := sonic.Unmarshal(resp.Body(), &out); err != nil {
+		return "", fmt.Errorf("HTTP %d，响应解析失败: %w", resp.StatusCode(), err)
+	}
+	if out.Error != nil {
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode(), out.Error.Message)
+	}
+	if resp.IsError() {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode())
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("响应中没有 choices")
+	}
+	text := strings.TrimSpace(thinkBlock.ReplaceAllString(out.Choices[0].Message.Content, ""))
+	if text == "" {
+		return "", fmt.Errorf("模型返回了空内容")
+	}
+	return text, nil
+}
@@ -0,0 +1,136 @@
+package recap
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/config"
+)
+
+// testSummary 周报测试使用的摘要
+func testSummary() Summary {
+	from := time.Date(2026, 10, 5, 0, 0, 0, 0, time.FixedZone("CST", 8*3600))
+	return Summary{
+		CharacterName:   "光之战士",
+		GroupName:       "拉诺西亚",
+		From:            from,
+		To:              from.AddDate(0, 0, 7),
+		PlayTimeMinutes: 6000,
+		PlayTimeDelta:   90,
+		LevelUps:        []LevelUp{{Job: "骑士", From: 89, To: 90}},
+		SignInDays:      7,
+	}
+}
+
+// llmServer 启动返回固定响应的 Chat Completions 服务，并校验请求
+func llmServer(t *testing.T, status int, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("请求 = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		data, _ := io.ReadAll(r.Body)
+		var req chatRequest
+		if err := sonic.Unmarshal(data, &req); err != nil {
+			t.Errorf("请求体解析失败: %v", err)
+		}
+		if req.Model != "qwen3" || req.Stream || len(req.Messages) != 2 || req.Messages[0].Role != "system" ||
+			!strings.Contains(req.Messages[1].Content, `"character_name": "光之战士"`) {
+			t.Errorf("请求体 = %s", data)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// llmSettings 指向 base 的 LLM 配置
+func llmSettings(base string) config.Settings {
+	st := config.DefaultSettings()
+	st.LLMBaseURL = base + "/v1/"
+	st.LLMModel = "qwen3"
+	st.LLMAPIKey = "sk-test"
+	st.LLMTimeout = config.Duration(5 * time.Second)
+	return st
+}
+
+func TestWrite(t *testing.T) {
+	content := func(text string) string {
+		data, _ := sonic.MarshalString(map[string]any{
+			"choices": []any{map[string]any{"message": map[string]any{"role": "assistant", "content": text}}},
+		})
+		return data
+	}
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		text   string
+		source string
+	}{
+		{"成功", http.StatusOK, content("  本周骑士升到了 90 级。\n"), "本周骑士升到了 90 级。", SourceLLM},
+		{"去除思考过程", http.StatusOK, content("<think>\n先看数据……\n</think>\n\n本周签到 7 天。"), "本周签到 7 天。", SourceLLM},
+		{"多段思考过程", http.StatusOK, content("<think>a</think>正文<think>b</think>"), "正文", SourceLLM},
+		{"只有思考过程", http.StatusOK, content("<think>想了很久</think>\n"), "", SourceTemplate},
+		{"空内容", http.StatusOK, content(""), "", SourceTemplate},
+		{"没有 choices", http.StatusOK, `{"choices":[]}`, "", SourceTemplate},
+		{"错误响应", http.StatusTooManyRequests, `{"error":{"message":"rate limited"}}`, "", SourceTemplate},
+		{"非 JSON 错误", http.StatusBadGateway, `<html>bad gateway</html>`, "", SourceTemplate},
+		{"状态码错误", http.StatusInternalServerError, content("不应采用"), "", SourceTemplate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := llmServer(t, tt.status, tt.body)
+			s := testSummary()
+			text, source := write(context.Background(), llmSettings(srv.URL), s)
+			if source != tt.source {
+				t.Fatalf("source = %q, want %q (%q)", source, tt.source, text)
+			}
+			want := tt.text
+			if tt.source == SourceTemplate {
+				want = Render(s)
+			}
+			if text != want {
+				t.Errorf("text = %q, want %q", text, want)
+			}
+		})
+	}
+}
+
+func TestCompleteError(t *testing.T) {
+	srv := llmServer(t, http.StatusTooManyRequests, `{"error":{"message":"rate limited"}}`)
+	s := testSummary()
+	_, err := complete(context.Background(), llmSettings(srv.URL), s, Render(s))
+	if err == nil || !strings.Contains(err.Error(), "429") || !strings.Contains(err.Error(), "rate limited") {
+		t.Errorf("complete() error = %v, 应包含状态码与错误信息", err)
+	}
+}
+
+func TestWriteWithoutLLM(t *testing.T) {
+	s := testSummary()
+	text, source := write(context.Background(), config.DefaultSettings(), s)
+	if source != SourceTemplate || text != Render(s) {
+		t.Errorf("write() = %q %q, 未配置 LLM 时应使用模板", text, source)
+	}
+}
+
+func TestWriteUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	s := testSummary()
+	if _, source := write(context.Background(), llmSettings(srv.URL), s); source != SourceTemplate {
+		t.Errorf("source = %q, 连接失败时应使用模板", source)
+	}
+}
@@ -0,0 +1,295 @@
+package recap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/models"
+	"llmaget/notify"
+)
+
+// 周报来源
+const (
+	SourceLLM      = "llm"
+	SourceTemplate = "template"
+)
+
+// Summary 单个角色在统计区间内的数据摘要，是模板与 LLM 的共同输入
+type Summary struct {
+	CharacterName string    `json:"character_name"`
+	UUID          string    `json:"uuid"`
+	AreaName      string    `json:"area_name"`
+	GroupName     string    `json:"group_name"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	// PlayTimeMinutes 区间末的累计游戏时长，PlayTimeDelta 为区间内增加的分钟数
+	PlayTimeMinutes int       `json:"play_time_minutes"`
+	PlayTimeDelta   int       `json:"play_time_delta"`
+	LevelUps        []LevelUp `json:"level_ups"`
+	NewAchievements []string  `json:"new_achievements"`
+	// SignInDays 签到成功的天数，SignInFailures 失败的签到任务数
+	SignInDays     int      `json:"sign_in_days"`
+	SignInFailures int      `json:"sign_in_failures"`
+	Rewards        []string `json:"rewards"`
+}
+
+// LevelUp 职业升级
+type LevelUp struct {
+	Job  string `json:"job"`
+	From int    `json:"from"`
+	To   int    `json:"to"`
+}
+
+// Recap 生成的周报
+type Recap struct {
+	CharacterName string `json:"character_name"`
+	// Source 为 llm 或 template
+	Source  string  `json:"source"`
+	Text    string  `json:"text"`
+	Summary Summary `json:"summary"`
+}
+
+// LastWeek 返回 now 之前最近一个完整自然周（周一 00:00 起）的区间，按业务时区划分
+func LastWeek(now time.Time) (time.Time, time.Time) {
+	now = clock.In(now)
+	y, m, d := now.Date()
+	// 周一为一周第一天
+	offset := (int(now.Weekday()) + 6) % 7
+	to := time.Date(y, m, d-offset, 0, 0, 0, 0, now.Location())
+	return to.AddDate(0, 0, -7), to
+}
+
+// Build 按角色汇总 [from, to) 内的快照、签到流水与奖励领取记录，区间内没有快照的角色不生成摘要
+func Build(src export.Sources, from, to time.Time) ([]Summary, error) {
+	type bounds struct {
+		before, first, last *models.Snapshot
+	}
+	chars := make(map[string]*bounds)
+	if src.Snapshots != nil {
+		err := src.Snapshots.Each(func(s models.Snapshot) error {
+			if s.Account != config.DefaultAccount || !s.Time.Before(to) {
+				return nil
+			}
+			key := s.UUID
+			if key == "" {
+				key = s.CharacterName
+			}
+			b := chars[key]
+			if b == nil {
+				b = &bounds{}
+				chars[key] = b
+			}
+			snap := s
+			if s.Time.Before(from) {
+				b.before = &snap
+				return nil
+			}
+			if b.first == nil {
+				b.first = &snap
+			}
+			b.last = &snap
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("读取快照失败: %w", err)
+		}
+	}
+
+	days, failures, err := signIns(src, from, to)
+	if err != nil {
+		return nil, err
+	}
+	rewards, err := claimedRewards(src, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []Summary
+	for _, b := range chars {
+		if b.last == nil {
+			continue
+		}
+		start := b.first
+		if b.before != nil {
+			start = b.before
+		}
+		end := b.last
+		out = append(out, Summary{
+			CharacterName:   end.CharacterName,
+			UUID:            end.UUID,
+			AreaName:        end.AreaName,
+			GroupName:       end.GroupName,
+			From:            from,
+			To:              to,
+			PlayTimeMinutes: end.PlayTimeMinutes,
+			PlayTimeDelta:   max(end.PlayTimeMinutes-start.PlayTimeMinutes, 0),
+			LevelUps:        levelUps(start.JobLevels, end.JobLevels),
+			NewAchievements: newAchievements(start.Achievements, end.Achievements),
+			SignInDays:      days,
+			SignInFailures:  failures,
+			Rewards:         rewards,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CharacterName < out[j].CharacterName })
+	return out, nil
+}
+
+// levelUps 比较两次快照的职业等级，区间起点没有的职业按 0 级计算
+func levelUps(before, after map[string]string) []LevelUp {
+	ups := []LevelUp{}
+	if len(before) == 0 {
+		return ups
+	}
+	for job, raw := range after {
+		to, err := strconv.Atoi(raw)
+		if err != nil {
+			continue
+		}
+		from, _ := strconv.Atoi(before[job])
+		if to > from {
+			ups = append(ups, LevelUp{Job: job, From: from, To: to})
+		}
+	}
+	sort.Slice(ups, func(i, j int) bool { return ups[i].Job < ups[j].Job })
+	return ups
+}
+
+// newAchievements 区间末出现、区间起点没有的成就
+func newAchievements(before, after []string) []string {
+	added := []string{}
+	for _, name := range after {
+		if !slices.Contains(before, name) && !slices.Contains(added, name) {
+			added = append(added, name)
+		}
+	}
+	return added
+}
+
+// signIns 统计区间内签到成功的天数与失败的签到任务数
+func signIns(src export.Sources, from, to time.Time) (int, int, error) {
+	if src.Ledger == nil {
+		return 0, 0, nil
+	}
+	days := make(map[string]bool)
+	failures := 0
+	err := src.Ledger.Each(func(job jobs.Job) error {
+		if job.Kind != jobs.KindSignIn && job.Kind != jobs.KindSignAndClaim {
+			return nil
+		}
+		if job.Account != config.DefaultAccount || job.CreatedAt.Before(from) || !job.CreatedAt.Before(to) {
+			return nil
+		}
+		switch job.Status {
+		case jobs.StatusSucceeded:
+			days[clock.In(job.CreatedAt).Format(export.DateLayout)] = true
+		case jobs.StatusFailed:
+			failures++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取签到流水失败: %w", err)
+	}
+	return len(days), failures, nil
+}
+
+// claimedRewards 区间内由本服务领取的奖励
+func claimedRewards(src export.Sources, from, to time.Time) ([]string, error) {
+	names := []string{}
+	if src.Rewards == nil {
+		return names, nil
+	}
+	months, err := src.Rewards.List()
+	if err != nil {
+		return nil, fmt.Errorf("读取奖励历史失败: %w", err)
+	}
+	for i := len(months) - 1; i >= 0; i-- {
+		for _, r := range months[i].Rewards {
+			if r.IsGet != 1 || r.ClaimedAt == nil || r.ClaimedAt.Before(from) || !r.ClaimedAt.Before(to) {
+				continue
+			}
+			names = append(names, fmt.Sprintf("%s ×%d", r.ItemName, r.Num))
+		}
+	}
+	return names, nil
+}
+
+// Generate 为每个角色生成周报，配置了 LLM 时由模型撰写，失败或未配置时使用模板
+func Generate(ctx context.Context, src export.Sources, from, to time.Time) ([]Recap, error) {
+	summaries, err := Build(src, from, to)
+	if err != nil {
+		return nil, err
+	}
+	recaps := make([]Recap, 0, len(summaries))
+	for _, s := range summaries {
+		text, source := write(ctx, config.Current(), s)
+		recaps = append(recaps, Recap{CharacterName: s.CharacterName, Source: source, Text: text, Summary: s})
+	}
+	return recaps, nil
+}
+
+// Deliver 通过通知渠道发送周报，返回成功发送的通知条数
+func Deliver(ctx context.Context, recaps []Recap) (int, error) {
+	total := 0
+	var errs []error
+	for _, r := range recaps {
+		n, err := notify.Send(ctx, notify.Message{
+			Event: notify.EventWeeklyRecap,
+			Title: fmt.Sprintf("📅 %s 的周报（%s）", r.CharacterName, period(r.Summary)),
+			Text:  r.Text,
+		})
+		total += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return total, errors.Join(errs...)
+}
+
+// period 统计区间的展示形式，结束日期取区间最后一天
+func period(s Summary) string {
+	from, to := clock.In(s.From), clock.In(s.To.Add(-time.Nanosecond))
+	return from.Format("01-02") + " ~ " + to.Format("01-02")
+}
+
+// Result 周报任务的结果
+type Result struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Recaps    []Recap   `json:"recaps"`
+	Delivered int       `json:"delivered"`
+	// DeliverError 部分渠道发送失败时的错误信息
+	DeliverError string `json:"deliver_error,omitempty"`
+}
+
+// Run 生成上一个自然周的周报并发送，所有渠道都发送失败时返回错误
+func Run(ctx context.Context, src export.Sources) (*Result, error) {
+	from, to := LastWeek(clock.Now())
+	recaps, err := Generate(ctx, src, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{From: from, To: to, Recaps: recaps}
+	if len(recaps) == 0 {
+		slog.InfoContext(ctx, "📅 本周期没有快照数据，不发送周报", "from", clock.FormatRFC3339(from))
+		return res, nil
+	}
+	res.Delivered, err = Deliver(ctx, recaps)
+	if err != nil {
+		if res.Delivered == 0 {
+			return nil, fmt.Errorf("发送周报失败: %w", err)
+		}
+		res.DeliverError = err.Error()
+	}
+	return res, nil
+}
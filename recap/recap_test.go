@@ -0,0 +1,182 @@
+package recap
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/models"
+	"llmaget/services"
+)
+
+func TestLastWeek(t *testing.T) {
+	sh := clock.Location()
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, sh)
+	tests := []struct {
+		name string
+		now  time.Time
+		from time.Time
+	}{
+		{"周一零点", monday, monday.AddDate(0, 0, -7)},
+		{"周日深夜", time.Date(2026, 10, 18, 23, 59, 0, 0, sh), monday.AddDate(0, 0, -7)},
+		{"周三", time.Date(2026, 10, 14, 9, 0, 0, 0, sh), monday.AddDate(0, 0, -7)},
+		// UTC 周日 16:30 是上海周一 00:30，上一个自然周已经结束
+		{"UTC 仍是周日", time.Date(2026, 10, 18, 16, 30, 0, 0, time.UTC), monday},
+		{"跨月", time.Date(2026, 11, 3, 8, 0, 0, 0, sh), time.Date(2026, 10, 26, 0, 0, 0, 0, sh)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := LastWeek(tt.now)
+			if !from.Equal(tt.from) || !to.Equal(tt.from.AddDate(0, 0, 7)) {
+				t.Errorf("LastWeek(%s) = [%s, %s), want [%s, %s)", tt.now, from, to, tt.from, tt.from.AddDate(0, 0, 7))
+			}
+			if from.Weekday() != time.Monday || from.Location() != sh {
+				t.Errorf("from = %s, 应为业务时区的周一", from)
+			}
+		})
+	}
+}
+
+// fixtureSources 在临时目录中写入 2026-10-12 所在周前后的快照、签到流水与奖励历史
+func fixtureSources(t *testing.T) export.Sources {
+	t.Helper()
+	dir := t.TempDir()
+	at := func(d, hh, mm int) time.Time {
+		return time.Date(2026, 10, d, hh, mm, 0, 0, clock.Location())
+	}
+
+	snaps := services.NewSnapshotLog(filepath.Join(dir, config.SnapshotsFileName))
+	for _, s := range []models.Snapshot{
+		{Time: at(11, 20, 0), UUID: "u1", CharacterName: "Alisaie", PlayTimeMinutes: 1000,
+			JobLevels: map[string]string{"骑士": "89", "白魔法师": "80"}, Achievements: []string{"初出茅庐"}},
+		{Time: at(11, 21, 0), UUID: "u3", CharacterName: "Urianger", PlayTimeMinutes: 300},
+		{Time: at(13, 8, 0), UUID: "u1", CharacterName: "Alisaie", PlayTimeMinutes: 1100,
+			JobLevels: map[string]string{"骑士": "90", "白魔法师": "80"}, Achievements: []string{"初出茅庐"}},
+		{Time: at(14, 8, 0), UUID: "u2", CharacterName: "Thancred", AreaName: "陆行鸟", GroupName: "拉诺西亚", PlayTimeMinutes: 500,
+			JobLevels: map[string]string{"绝枪战士": "70"}},
+		{Time: at(15, 8, 0), Account: "other", UUID: "u1", CharacterName: "Alisaie", PlayTimeMinutes: 50000},
+		{Time: at(18, 23, 59), UUID: "u1", CharacterName: "Alisaie", AreaName: "猫小胖", GroupName: "紫水栈桥", PlayTimeMinutes: 1225,
+			JobLevels:    map[string]string{"骑士": "90", "白魔法师": "81", "画家": "5", "暗黑骑士": "?"},
+			Achievements: []string{"初出茅庐", "精英狩猎", "精英狩猎"}},
+		{Time: at(19, 0, 0), UUID: "u1", CharacterName: "Alisaie", PlayTimeMinutes: 9999},
+	} {
+		if s.Account == "" {
+			s.Account = config.DefaultAccount
+		}
+		if err := snaps.Append(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ledger := jobs.NewLedger(filepath.Join(dir, config.LedgerFileName))
+	for _, j := range []jobs.Job{
+		{Kind: jobs.KindSignIn, Status: jobs.StatusSucceeded, CreatedAt: at(11, 23, 59)},
+		{Kind: jobs.KindSignIn, Status: jobs.StatusSucceeded, CreatedAt: at(12, 0, 0)},
+		{Kind: jobs.KindSignAndClaim, Status: jobs.StatusSucceeded, CreatedAt: at(12, 21, 0)},
+		{Kind: jobs.KindSignIn, Status: jobs.StatusSucceeded, CreatedAt: at(14, 9, 0)},
+		{Kind: jobs.KindSignIn, Status: jobs.StatusFailed, CreatedAt: at(15, 9, 0)},
+		{Kind: jobs.KindRewardSweep, Status: jobs.StatusFailed, CreatedAt: at(16, 23, 30)},
+		{Kind: jobs.KindSignIn, Status: jobs.StatusSucceeded, CreatedAt: at(17, 9, 0), Account: "other"},
+		{Kind: jobs.KindSignIn, Status: jobs.StatusSucceeded, CreatedAt: at(19, 0, 0)},
+	} {
+		if j.Account == "" {
+			j.Account = config.DefaultAccount
+		}
+		if err := ledger.Append(j); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := services.NewRewardHistory(filepath.Join(dir, config.RewardsFileName))
+	rewards := []models.SignReward{
+		{ID: 1, ItemName: "金碟币", Num: 100},
+		{ID: 2, ItemName: "陆行鸟饲料", Num: 3},
+		{ID: 3, ItemName: "幻化衣", Num: 1, IsGet: 1},
+	}
+	if err := history.Record("2026-10", rewards, map[int]time.Time{2: at(5, 9, 0)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := history.Record("2026-10", rewards, map[int]time.Time{1: at(13, 9, 0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	return export.Sources{Snapshots: snaps, Ledger: ledger, Rewards: history}
+}
+
+func TestBuild(t *testing.T) {
+	from, to := LastWeek(time.Date(2026, 10, 19, 12, 0, 0, 0, clock.Location()))
+	got, err := Build(fixtureSources(t), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Summary{
+		{
+			CharacterName: "Alisaie", UUID: "u1", AreaName: "猫小胖", GroupName: "紫水栈桥",
+			From: from, To: to,
+			// 以区间前最近的快照为起点
+			PlayTimeMinutes: 1225,
+			PlayTimeDelta:   225,
+			LevelUps:        []LevelUp{{"画家", 0, 5}, {"白魔法师", 80, 81}, {"骑士", 89, 90}},
+			NewAchievements: []string{"精英狩猎"},
+			SignInDays:      2,
+			SignInFailures:  1,
+			Rewards:         []string{"金碟币 ×100"},
+		},
+		{
+			CharacterName: "Thancred", UUID: "u2", AreaName: "陆行鸟", GroupName: "拉诺西亚",
+			From: from, To: to,
+			// 区间内只有一条快照，没有可比较的起点
+			PlayTimeMinutes: 500,
+			PlayTimeDelta:   0,
+			LevelUps:        []LevelUp{},
+			NewAchievements: []string{},
+			SignInDays:      2,
+			SignInFailures:  1,
+			Rewards:         []string{"金碟币 ×100"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	from, to := LastWeek(time.Date(2026, 10, 19, 12, 0, 0, 0, clock.Location()))
+	got, err := Build(export.Sources{}, from, to)
+	if err != nil || len(got) != 0 {
+		t.Errorf("Build() = %v, %v, 没有数据来源时应返回空", got, err)
+	}
+
+	// 区间内没有快照的角色不生成摘要
+	got, err = Build(fixtureSources(t), from.AddDate(0, 0, 14), to.AddDate(0, 0, 14))
+	if err != nil || len(got) != 0 {
+		t.Errorf("Build() = %v, %v, 区间内没有快照时应返回空", got, err)
+	}
+}
+
+func TestGenerateFallsBackToTemplate(t *testing.T) {
+	if config.Current().LLMBaseURL != "" {
+		t.Skip("当前配置启用了 LLM")
+	}
+	from, to := LastWeek(time.Date(2026, 10, 19, 12, 0, 0, 0, clock.Location()))
+	recaps, err := Generate(context.Background(), fixtureSources(t), from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recaps) != 2 || recaps[0].Source != SourceTemplate || recaps[0].Text != Render(recaps[0].Summary) {
+		t.Fatalf("Generate() = %+v", recaps)
+	}
+	for _, want := range []string{"紫水栈桥", "10-12 ~ 10-18", "+3 小时 45 分钟", "白魔法师 80→81", "精英狩猎", "成功 2 天，失败 1 次", "金碟币 ×100"} {
+		if !strings.Contains(recaps[0].Text, want) {
+			t.Errorf("周报缺少 %q:\n%s", want, recaps[0].Text)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package recap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Render 使用固定模板生成周报，相同输入总是得到相同输出
+func Render(s Summary) string {
+	var sb strings.Builder
+	name := s.CharacterName
+	if s.GroupName != "" {
+		name += "@" + s.GroupName
+	}
+	fmt.Fprintf(&sb, "📅 %s 周报（%s）", name, period(s))
+
+	fmt.Fprintf(&sb, "\n⏱️ 游戏时长：本周 +%s，累计 %d 小时", minutes(s.PlayTimeDelta), s.PlayTimeMinutes/60)
+
+	if len(s.LevelUps) > 0 {
+		ups := make([]string, 0, len(s.LevelUps))
+		for _, u := range s.LevelUps {
+			ups = append(ups, fmt.Sprintf("%s %d→%d", u.Job, u.From, u.To))
+		}
+		sb.WriteString("\n⬆️ 职业升级：" + strings.Join(ups, "、"))
+	} else {
+		sb.WriteString("\n⬆️ 职业升级：无")
+	}
+
+	if len(s.NewAchievements) > 0 {
+		sb.WriteString("\n🏆 新成就：" + strings.Join(s.NewAchievements, "、"))
+	}
+
+	fmt.Fprintf(&sb, "\n📝 签到：成功 %d 天", s.SignInDays)
+	if s.SignInFailures > 0 {
+		fmt.Fprintf(&sb, "，失败 %d 次", s.SignInFailures)
+	}
+
+	if len(s.Rewards) > 0 {
+		sb.WriteString("\n🎁 领取奖励：" + strings.Join(s.Rewards, "、"))
+	}
+	return sb.String()
+}
+
+// minutes 分钟数格式化为 "X 小时 Y 分钟"
+func minutes(m int) string {
+	if m < 60 {
+		return fmt.Sprintf("%d 分钟", m)
+	}
+	return fmt.Sprintf("%d 小时 %d 分钟", m/60, m%60)
+}
@@ -53,8 +53,7 @@ start() {
     fi
     
     echo -e "${GREEN}启动服务...${NC}"
-    cd "$APP_DIR"
-    nohup "$APP_PATH" >> "$LOG_FILE" 2>&1 &
+    nohup "$APP_PATH" serve --config "$APP_DIR/config.json" >> "$LOG_FILE" 2>&1 &
     echo $! > "$PID_FILE"
     
     sleep 1
@@ -81,8 +80,8 @@ stop() {
     echo -e "${GREEN}停止服务 (PID: $pid)...${NC}"
     kill "$pid"
     
-    # 等待进程退出
-    for i in {1..10}; do
+    # 等待进程退出（服务端优雅关闭宽限期为 20 秒）
+    for i in {1..30}; do
         if ! is_running; then
             echo -e "${GREEN}✓ 服务已停止${NC}"
             rm -f "$PID_FILE"
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/export"
+	"llmaget/jobs"
+	"llmaget/logging"
+	"llmaget/recap"
+	"llmaget/services"
+	"llmaget/sites"
+)
+
+// registerJobs 注册所有站点的任务以及周报任务
+func registerJobs(m *jobs.Manager, reg *sites.Registry, ff14Svc *services.FF14Service) {
+	reg.RegisterJobs(m)
+
+	recapSources := export.Sources{
+		Snapshots: ff14Svc.Snapshots(),
+		Rewards:   ff14Svc.RewardHistory(),
+		Ledger:    m.Ledger(),
+	}
+	m.Register(jobs.KindWeeklyRecap, func(ctx context.Context) (any, error) {
+		return recap.Run(ctx, recapSources)
+	})
+}
+
+// scheduler 定时任务调度器，到点后向任务管理器提交任务
+type scheduler struct {
+	jobs  *jobs.Manager
+	sites *sites.Registry
+	wg    sync.WaitGroup
+}
+
+// newScheduler 创建调度器
+func newScheduler(m *jobs.Manager, reg *sites.Registry) *scheduler {
+	return &scheduler{jobs: m, sites: reg}
+}
+
+// Start 提交各站点的启动任务并启动定时任务
+func (s *scheduler) Start(ctx context.Context) {
+	st := config.Current()
+	for _, a := range s.sites.All() {
+		ready := func() error { return sites.Ready(a) }
+		for _, t := range a.Tasks() {
+			if t.OnStart {
+				s.submit(t.Kind, ready)
+			}
+			switch {
+			case t.Every != nil:
+				s.loop(ctx, t.Kind, t.Every(st), ready)
+			case t.Next != nil:
+				s.calendarLoop(ctx, t.Kind, t.Next, ready)
+			}
+		}
+	}
+	s.calendarLoop(ctx, jobs.KindWeeklyRecap, nextRecap, nil)
+}
+
+// loop 按固定间隔提交任务，直到 ctx 结束
+func (s *scheduler) loop(ctx context.Context, kind string, interval time.Duration, ready func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		slog.Info("⏰ 定时任务启动", "task", kind, "interval", interval)
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("⏹️ 定时任务停止", "task", kind)
+				return
+			case <-ticker.C:
+				s.submit(kind, ready)
+			}
+		}
+	}()
+}
+
+// calendarLoop 按 next 计算的日历时间提交任务，直到 ctx 结束
+func (s *scheduler) calendarLoop(ctx context.Context, kind string, next func(time.Time) time.Time, ready func() error) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		for {
+			now := clock.Now()
+			at := next(now)
+			slog.Info("⏰ 下次执行", "task", kind, "at", clock.FormatRFC3339(at))
+
+			timer := time.NewTimer(at.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				slog.Info("⏹️ 定时任务停止", "task", kind)
+				return
+			case <-timer.C:
+				s.submit(kind, ready)
+			}
+		}
+	}()
+}
+
+// 周报时间：每周一 09:00 汇总上一个自然周
+const (
+	recapWeekday = time.Monday
+	recapHour    = 9
+)
+
+// nextRecap 计算 now 之后最近的周报时间，按 now 所在时区（业务时区）划分
+func nextRecap(now time.Time) time.Time {
+	y, m, d := now.Date()
+	days := (int(recapWeekday) - int(now.Weekday()) + 7) % 7
+	at := time.Date(y, m, d+days, recapHour, 0, 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 7)
+	}
+	return at
+}
+
+// submit 提交一次定时任务，ready 不为空且返回错误时跳过本次执行
+func (s *scheduler) submit(kind string, ready func() error) {
+	ctx := logging.NewContext(context.Background())
+	if ready != nil {
+		if err := ready(); err != nil {
+			slog.InfoContext(ctx, "⏭️ 跳过定时任务", "task", kind, "reason", err)
+			return
+		}
+	}
+	slog.InfoContext(ctx, "⏰ 任务触发", "task", kind)
+	if _, _, err := s.jobs.Submit(ctx, kind, config.DefaultAccount, jobs.TriggerScheduled); err != nil {
+		slog.ErrorContext(ctx, "❌ 任务提交失败", "task", kind, "error", err)
+	}
+}
+
+// Wait 等待调度循环退出
+func (s *scheduler) Wait() {
+	s.wg.Wait()
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"llmaget/bot"
+	"llmaget/config"
+	"llmaget/handlers"
+	"llmaget/jobs"
+	"llmaget/logging"
+	"llmaget/services"
+	"llmaget/sites"
+	"llmaget/sites/ff14"
+	"llmaget/workflow"
+)
+
+// runServe 启动 HTTP 服务与定时任务，收到退出信号后优雅关闭
+func runServe() int {
+	slog.Info("🚀 FF14 石之家服务启动...")
+
+	// 加载配置
+	state := config.GetState()
+	if err := state.Load(); err != nil {
+		slog.Error("❌ 配置加载失败，使用默认配置运行，请修正配置文件", "error", err)
+	}
+	st := state.Settings()
+	logging.Setup(st.LogFormat, st.LogLevel)
+
+	// 监听退出信号
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// 监听配置文件变更
+	go state.Watch(ctx, st.ConfigWatchInterval.D())
+
+	// 创建服务
+	ff14Svc := services.NewFF14Service()
+
+	siteReg := newSiteRegistry(ff14Svc)
+
+	// 启动任务管理器
+	jobMgr := jobs.NewManager(st.JobsFile())
+	jobMgr.SetLedger(jobs.NewLedger(st.LedgerFile()))
+	registerJobs(jobMgr, siteReg, ff14Svc)
+	jobMgr.Start(2)
+
+	// 启动定时任务（包含首次数据获取）
+	sched := newScheduler(jobMgr, siteReg)
+	sched.Start(ctx)
+
+	// 设置 Gin 模式
+	gin.SetMode(gin.ReleaseMode)
+
+	// 创建 Gin 引擎
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(requestLogger())
+
+	// CORS 中间件
+	r.Use(corsMiddleware())
+
+	// 注册路由
+	handler := handlers.NewHandler(ff14Svc, jobMgr, siteReg)
+	handler.RegisterRoutes(r)
+	bot.New(ff14Svc, jobMgr).RegisterRoutes(r)
+
+	srv := &http.Server{
+		Addr:    st.ServerPort,
+		Handler: r,
+	}
+
+	// 启动服务器
+	exitCode := 0
+	go func() {
+		slog.Info("🌐 HTTP服务器启动", "addr", st.ServerPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("❌ HTTP服务器启动失败", "error", err)
+			exitCode = 1
+			stop()
+		}
+	}()
+
+	<-ctx.Done()
+	slog.Info("🛑 收到退出信号，开始优雅关闭", "grace", st.ShutdownGrace)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), st.ShutdownGrace.D())
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("❌ HTTP服务器关闭失败", "error", err)
+	}
+	sched.Wait()
+	jobMgr.Shutdown(shutdownCtx)
+
+	if err := state.Flush(); err != nil {
+		slog.Error("❌ 状态写入磁盘失败", "error", err)
+	}
+
+	slog.Info("👋 服务已退出")
+	return exitCode
+}
+
+// newSiteRegistry 注册站点适配器，接入新站点时在此添加
+func newSiteRegistry(ff14Svc *services.FF14Service) *sites.Registry {
+	return sites.NewRegistry(
+		ff14.New(ff14Svc, loadWorkflows()...),
+	)
+}
+
+// loadWorkflows 加载 workflow_dir 下的工作流定义，无效的定义记录日志后跳过
+func loadWorkflows() []*workflow.Definition {
+	dir := config.Current().WorkflowPath()
+	defs, err := workflow.Load(dir)
+	if err != nil {
+		slog.Error("❌ 部分工作流定义无效，已跳过", "dir", dir, "error", err)
+	}
+	if len(defs) > 0 {
+		slog.Info("📜 已加载工作流", "dir", dir, "count", len(defs))
+	}
+	return defs
+}
+
+// requestLogger 请求日志中间件，为每个请求分配关联 ID
+func requestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		id := c.GetHeader("X-Request-ID")
+		if id == "" {
+			id = logging.NewCorrelationID()
+		}
+		ctx := logging.WithCorrelationID(c.Request.Context(), id)
+		c.Request = c.Request.WithContext(ctx)
+		c.Header("X-Request-ID", id)
+
+		c.Next()
+
+		slog.InfoContext(ctx, "HTTP请求",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", c.Writer.Status(),
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+		)
+	}
+}
+
+// corsMiddleware CORS 中间件
+func corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-Backup-Passphrase, Mcp-Session-Id, Mcp-Protocol-Version, Last-Event-ID")
+		c.Header("Access-Control-Expose-Headers", "Mcp-Session-Id")
+
+		if c.Request.Method == "OPTIONS" {
+			// 书签从公网页面请求部署在内网或本机的服务时，Chrome 的私有网络访问预检需要该响应头
+			c.Header("Access-Control-Allow-Private-Network", "true")
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/metrics"
+	"llmaget/models"
+)
+
+// ErrCircuitOpen 上游连续失败，熔断期间请求直接失败
+var ErrCircuitOpen = errors.New("上游连续失败，已熔断，请稍后重试")
+
+// 熔断器状态
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// breaker 上游熔断器
+//
+// 连续失败达到 breaker_threshold 次后打开，期间请求直接返回 ErrCircuitOpen；
+// 打开 breaker_cooldown 后进入半开状态，只放行一个探测请求，成功则关闭，失败则重新打开。
+type breaker struct {
+	state *config.AppState
+
+	mu       sync.Mutex
+	current  string
+	failures int
+	openedAt time.Time
+	// probeAt 半开状态下探测请求的发出时间，探测超过冷却时间未返回结果时允许再次探测
+	probeAt time.Time
+}
+
+// newBreaker 创建熔断器
+func newBreaker(state *config.AppState) *breaker {
+	metrics.CircuitState.Set(0)
+	return &breaker{state: state, current: BreakerClosed}
+}
+
+// Allow 判断是否放行请求
+func (b *breaker) Allow() error {
+	cooldown := b.state.Settings().BreakerCooldown.D()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.current {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < cooldown {
+			return ErrCircuitOpen
+		}
+		b.setStateLocked(BreakerHalfOpen)
+		b.probeAt = now
+		slog.Info("🔌 熔断冷却结束，发送探测请求")
+		return nil
+	case BreakerHalfOpen:
+		if now.Sub(b.probeAt) < cooldown {
+			return ErrCircuitOpen
+		}
+		b.probeAt = now
+		return nil
+	default:
+		return nil
+	}
+}
+
+// Success 记录一次成功，关闭熔断器
+func (b *breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	if b.current != BreakerClosed {
+		b.setStateLocked(BreakerClosed)
+		slog.Info("✅ 上游恢复，熔断关闭")
+	}
+}
+
+// Failure 记录一次失败，达到阈值或探测失败时打开熔断器
+func (b *breaker) Failure(reason string) {
+	threshold := b.state.Settings().BreakerThreshold
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.current == BreakerHalfOpen || (b.current == BreakerClosed && b.failures >= threshold) {
+		b.openedAt = time.Now()
+		b.setStateLocked(BreakerOpen)
+		slog.Warn("🔌 上游连续失败，熔断打开", "failures", b.failures, "reason", reason, "cooldown", b.state.Settings().BreakerCooldown.D())
+	}
+}
+
+// setStateLocked 切换状态并更新指标（调用方需持有锁）
+func (b *breaker) setStateLocked(state string) {
+	b.current = state
+	switch state {
+	case BreakerOpen:
+		metrics.CircuitState.Set(2)
+	case BreakerHalfOpen:
+		metrics.CircuitState.Set(1)
+	default:
+		metrics.CircuitState.Set(0)
+	}
+}
+
+// Status 熔断器状态快照
+func (b *breaker) Status() models.BreakerStatus {
+	cooldown := b.state.Settings().BreakerCooldown.D()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := models.BreakerStatus{State: b.current, Failures: b.failures}
+	if b.current != BreakerClosed {
+		status.OpenedAt = clock.FormatRFC3339(b.openedAt)
+		status.RetryAt = clock.FormatRFC3339(b.openedAt.Add(cooldown))
+	}
+	return status
+}
+
+// breakerTransport 在每次实际发出的请求（含重试）上记录成功与失败
+type breakerTransport struct {
+	base    http.RoundTripper
+	breaker *breaker
+}
+
+// RoundTrip 实现 http.RoundTripper
+//
+// 网络错误与 5xx 计为失败；429 属于限流，不计入熔断；调用方取消的请求不计结果。
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil:
+		if req.Context().Err() == nil && !errors.Is(err, context.Canceled) {
+			t.breaker.Failure(err.Error())
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		t.breaker.Failure(resp.Status)
+	case resp.StatusCode != http.StatusTooManyRequests:
+		t.breaker.Success()
+	}
+	return resp, err
+}
+
+// breakClient 为 resty 客户端挂载熔断器，需在限速之前挂载，熔断时不占用令牌
+func breakClient(client *resty.Client, b *breaker) {
+	base := client.GetClient().Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.SetTransport(&breakerTransport{base: base, breaker: b})
+
+	client.OnBeforeRequest(func(_ *resty.Client, _ *resty.Request) error {
+		return b.Allow()
+	})
+}
+
+// BreakerStatus 获取上游熔断器状态
+func (s *FF14Service) BreakerStatus() models.BreakerStatus {
+	return s.breaker.Status()
+}
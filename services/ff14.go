@@ -1,25 +1,34 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os"
+	"log/slog"
 	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bytedance/sonic"
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 
+	"llmaget/cache"
 	"llmaget/config"
+	"llmaget/metrics"
 	"llmaget/models"
+	"llmaget/store"
 )
 
 // FF14Service FF14 石之家服务
 type FF14Service struct {
-	client *resty.Client
-	state  *config.AppState
+	client    *resty.Client
+	state     *config.AppState
+	history   *RewardHistory
+	breaker   *breaker
+	cache     *cache.Cache
+	snapshots *SnapshotLog
+	shop      *ShopLog
 }
 
 // NewFF14Service 创建 FF14 服务实例
@@ -29,16 +38,32 @@ func NewFF14Service() *FF14Service {
 		SetRetryCount(3).
 		SetRetryWaitTime(1 * time.Second).
 		SetRetryMaxWaitTime(5 * time.Second)
+	instrumentClient(client)
 
+	state := config.GetState()
+	b := newBreaker(state)
+	breakClient(client, b)
+	limitClient(client, newRateLimiter(state), state)
+	st := state.Settings()
+	cacheFile := ""
+	if st.CacheFile != "" {
+		cacheFile = st.DataPath(st.CacheFile)
+	}
 	return &FF14Service{
-		client: client,
-		state:  config.GetState(),
+		client:    client,
+		state:     state,
+		history:   NewRewardHistory(st.RewardsFile()),
+		breaker:   b,
+		cache:     cache.New(cacheFile),
+		snapshots: NewSnapshotLog(st.SnapshotsFile()),
+		shop:      NewShopLog(st.ShopFile()),
 	}
 }
 
 // buildURL 构建完整 URL
 func (s *FF14Service) buildURL(path string) string {
-	return fmt.Sprintf("%s://%s%s", config.Scheme, config.BaseURL, path)
+	st := s.state.Settings()
+	return fmt.Sprintf("%s://%s%s", st.Scheme, st.BaseURL, path)
 }
 
 // setCommonHeaders 设置通用请求头
@@ -51,66 +76,88 @@ func (s *FF14Service) setCommonHeaders(req *resty.Request) *resty.Request {
 		SetHeader("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
 }
 
+// logBody 在 debug 级别输出截断后的响应内容
+func logBody(ctx context.Context, msg string, body []byte) {
+	slog.DebugContext(ctx, msg, "body", truncateString(string(body), 500))
+}
+
 // GetBindInfo 获取角色绑定信息
-func (s *FF14Service) GetBindInfo() error {
-	log.Println("🚀 开始获取数据...")
+func (s *FF14Service) GetBindInfo(ctx context.Context) error {
+	slog.InfoContext(ctx, "🚀 开始获取角色绑定信息")
 
 	if !s.state.HasCookie() {
-		log.Println("⚠️ Cookie未配置，跳过数据获取")
+		slog.WarnContext(ctx, "⚠️ Cookie未配置，跳过数据获取")
 		return fmt.Errorf("cookie未配置")
 	}
 
-	req := s.setCommonHeaders(s.client.R())
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 	resp, err := req.
 		SetQueryParams(map[string]string{
 			"platform": "2",
 			"tempsuid": uuid.New().String(),
 		}).
-		Get(s.buildURL(config.BindInfoPath))
+		Get(s.buildURL(s.state.Settings().BindInfoPath))
 
 	if err != nil {
-		log.Printf("❌ 请求失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
 		return fmt.Errorf("请求失败: %w", err)
 	}
 
-	log.Printf("📥 收到响应 (状态码: %d, 长度: %d)", resp.StatusCode(), len(resp.Body()))
+	slog.InfoContext(ctx, "📥 收到响应", "status", resp.StatusCode(), "size", len(resp.Body()))
 
-	if err := s.saveResponse(resp.Body()); err != nil {
-		log.Printf("❌ 保存响应失败: %v", err)
+	if err := s.saveResponse(ctx, resp.Body()); err != nil {
+		slog.ErrorContext(ctx, "❌ 保存响应失败", "error", err)
 		return fmt.Errorf("保存响应失败: %w", err)
 	}
 
-	log.Printf("✅ 数据获取完成! 结果已保存到 %s", config.OutputFile)
+	slog.InfoContext(ctx, "✅ 数据获取完成", "file", s.state.Settings().OutputFile())
 	return nil
 }
 
-func (s *FF14Service) SaveMyBaseInfo() error {
-
-	infoResp, err := s.GetUserInfo("")
+// SaveMyBaseInfo 获取并保存当前登录用户的基础信息
+func (s *FF14Service) SaveMyBaseInfo(ctx context.Context) error {
+	infoResp, err := s.GetUserInfo(ctx, "")
 	if err != nil {
-		log.Printf("❌ 获取数据失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 获取数据失败", "error", err)
 		return fmt.Errorf("获取数据失败: %w", err)
 	}
 
+	// 会话失效等业务错误时保留上次成功的数据，也不更新最近成功时间
+	if infoResp.Code != 10000 {
+		slog.WarnContext(ctx, "⚠️ 获取数据失败，可能是 Cookie 已失效", "code", infoResp.Code, "msg", infoResp.Msg)
+		return fmt.Errorf("获取数据失败: %d %s", infoResp.Code, infoResp.Msg)
+	}
+
 	if err := s.saveBaseInfo(infoResp); err != nil {
-		log.Printf("❌ 保存响应失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 保存响应失败", "error", err)
 		return fmt.Errorf("保存响应失败: %w", err)
 	}
+	if err := s.snapshots.Append(newSnapshot(infoResp)); err != nil {
+		slog.WarnContext(ctx, "⚠️ 保存快照失败", "error", err)
+	}
 
-	log.Printf("✅ 数据获取完成! 结果已保存到 %s", config.OutputFile)
+	metrics.MarkNow(metrics.LastFetchSuccess)
+	if len(infoResp.Data.CharacterDetail) > 0 {
+		metrics.PlayTimeMinutes.
+			WithLabelValues(infoResp.Data.CharacterName, infoResp.Data.GroupName).
+			Set(float64(ParsePlayTimeToMinutes(infoResp.Data.CharacterDetail[0].PlayTime)))
+	}
+
+	slog.InfoContext(ctx, "✅ 数据获取完成", "file", s.state.Settings().OutputFile())
 	return nil
 }
 
-func (s *FF14Service) GetUserInfo(userId string) (*models.UserInfoResp, error) {
-	log.Println("🚀 开始获取数据...")
+// GetUserInfo 获取用户信息，userId 为空时获取当前登录用户
+func (s *FF14Service) GetUserInfo(ctx context.Context, userId string) (*models.UserInfoResp, error) {
+	slog.InfoContext(ctx, "🚀 开始获取用户信息", "uuid", userId)
 
 	if !s.state.HasCookie() {
-		log.Println("⚠️ Cookie未配置，跳过数据获取")
+		slog.WarnContext(ctx, "⚠️ Cookie未配置，跳过数据获取")
 		return nil, fmt.Errorf("cookie未配置")
 	}
 
-	req := s.setCommonHeaders(s.client.R())
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 	params := map[string]string{
 		"tempsuid": uuid.New().String(),
@@ -118,180 +165,222 @@ func (s *FF14Service) GetUserInfo(userId string) (*models.UserInfoResp, error) {
 	if userId != "" {
 		params["uuid"] = userId
 	} else {
-		log.Printf("未提供用户id，获取当前登录用户信息")
+		slog.DebugContext(ctx, "未提供用户id，获取当前登录用户信息")
 	}
 
 	resp, err := req.
 		SetQueryParams(params).
-		Get(s.buildURL(config.UserInfoPath))
+		Get(s.buildURL(s.state.Settings().UserInfoPath))
 
 	if err != nil {
-		log.Printf("❌ 请求失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 
-	log.Printf("📥 收到响应 (状态码: %d, 长度: %d)", resp.StatusCode(), len(resp.Body()))
+	slog.InfoContext(ctx, "📥 收到响应", "status", resp.StatusCode(), "size", len(resp.Body()))
+	logBody(ctx, "用户信息响应", resp.Body())
+
 	var userInfoResp models.UserInfoResp
 	if err := sonic.Unmarshal(resp.Body(), &userInfoResp); err != nil {
-		log.Printf("❌ 解析响应失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err)
+	}
+	if userId == "" {
+		metrics.SetSessionValid(userInfoResp.Code == 10000)
 	}
 
 	return &userInfoResp, nil
 }
 
-func (s *FF14Service) SignAndGetSignReward() ([]byte, error) {
-	log.Printf("开始签到并检测奖励...")
-	_, err := s.SignIn()
-	if err != nil {
-		log.Printf("❌ 签到时发生错误")
-		return nil, err
-	}
-
-	rewardsBody, err := s.SignRewardList()
+// SignAndGetSignReward 签到并领取当月所有可领取的奖励
+func (s *FF14Service) SignAndGetSignReward(ctx context.Context) ([]byte, error) {
+	slog.InfoContext(ctx, "开始签到并检测奖励")
+	_, err := s.SignIn(ctx)
 	if err != nil {
-		log.Printf("❌ 获取奖励列表时发生错误")
+		slog.ErrorContext(ctx, "❌ 签到时发生错误", "error", err)
 		return nil, err
 	}
-
-	respMap := map[string][]string{
-		"unavailable": {},
-		"available":   {},
-		"claimed":     {},
-		"success":     {},
-		"fail":        {},
-	}
-
-	for _, reward := range rewardsBody.Data {
-		if reward.IsGet == 0 {
-			respMap["available"] = append(respMap["available"], reward.ItemName)
-			log.Printf("奖励 %s 可领取！", reward.ItemName)
-			resp, err := s.GetSignReward(reward.ID)
-			if err != nil {
-				respMap["fail"] = append(respMap["fail"], reward.ItemName)
-				log.Printf("❌ 奖励 %s 领取失败！错误：%s 响应：%s", reward.ItemName, err, string(resp))
-				return nil, err
-			} else {
-				respMap["success"] = append(respMap["success"], reward.ItemName)
-				log.Printf("✅ 奖励 %s 领取成功！响应：%s", reward.ItemName, string(resp))
-			}
-			continue
-		} else if reward.IsGet == 1 {
-			respMap["claimed"] = append(respMap["claimed"], reward.ItemName)
-			log.Printf("奖励 %s 已领取，跳过...", reward.ItemName)
-			continue
-		} else {
-			respMap["unavailable"] = append(respMap["unavailable"], reward.ItemName)
-			log.Printf("奖励 %s 暂未达到领取条件，跳过...", reward.ItemName)
-			continue
-		}
-	}
-	log.Printf("奖励领取处理完成")
-	resp, err := sonic.Marshal(respMap)
-	if err != nil {
-		log.Fatalf("map转换json失败 %v", &err)
-		return nil, err
-	}
-	return resp, nil
+	return s.ClaimRewards(ctx, "")
 }
 
 // SignIn 执行签到
-func (s *FF14Service) SignIn() ([]byte, error) {
-	log.Printf("📝 开始尝试打卡...")
+func (s *FF14Service) SignIn(ctx context.Context) ([]byte, error) {
+	slog.InfoContext(ctx, "📝 开始尝试打卡")
 
 	if !s.state.HasCookie() {
-		log.Println("⚠️ Cookie未配置")
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
 		return nil, fmt.Errorf("cookie未配置")
 	}
 
-	req := s.setCommonHeaders(s.client.R())
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 	resp, err := req.
 		SetQueryParam("tempsuid", uuid.New().String()).
-		Post(s.buildURL(config.SignInPath))
+		Post(s.buildURL(s.state.Settings().SignInPath))
 
 	if err != nil {
-		log.Printf("❌ 请求失败: %v", err)
+		metrics.ObserveResult(metrics.SignIns, false)
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 
-	log.Printf("📔 签到响应: %s", string(resp.Body()))
+	logBody(ctx, "📔 签到响应", resp.Body())
 
+	status, ok := parseUpstreamStatus(resp.Body())
+	signed := ok && status.Code == 10000
+	metrics.ObserveResult(metrics.SignIns, signed)
+	if signed {
+		metrics.MarkNow(metrics.LastSignSuccess)
+	}
+	slog.InfoContext(ctx, "📔 签到完成", "code", status.Code, "success", signed)
+
+	if !signed {
+		if ok && alreadySigned(status.Msg) {
+			slog.InfoContext(ctx, "📔 今日已签到", "msg", status.Msg)
+			return resp.Body(), nil
+		}
+		if !ok {
+			return nil, fmt.Errorf("签到失败: 无法解析响应")
+		}
+		return nil, fmt.Errorf("签到失败: %d %s", status.Code, status.Msg)
+	}
 	return resp.Body(), nil
 }
 
-func (s *FF14Service) SignRewardList() (*models.SignInRewards, error) {
-	log.Printf("📝 获取签到奖励列表...")
+// alreadySigned 判断签到失败是否只是今日已签到
+//
+// 石之家重复签到时返回非 10000 的业务码，只能按提示信息识别。
+func alreadySigned(msg string) bool {
+	return strings.Contains(msg, "已签到") || strings.Contains(msg, "已经签到")
+}
+
+// SignRewardList 获取签到奖励列表，month 格式为 2006-01，为空时取当月
+func (s *FF14Service) SignRewardList(ctx context.Context, month string) (*models.SignInRewards, error) {
+	month, err := NormalizeMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "📝 获取签到奖励列表", "month", month)
 
 	if !s.state.HasCookie() {
-		log.Println("⚠️ Cookie未配置")
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
 		return nil, fmt.Errorf("cookie未配置")
 	}
 
-	req := s.setCommonHeaders(s.client.R())
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 	resp, err := req.
 		SetQueryParams(map[string]string{
 			"tempsuid": uuid.New().String(),
-			"month":    time.Now().Format("2006-01"),
+			"month":    month,
 		}).
-		Get(s.buildURL(config.SignRewardsPath))
+		Get(s.buildURL(s.state.Settings().SignRewardsPath))
 
 	if err != nil {
-		log.Printf("❌ 请求失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 
-	log.Printf("📔 签到奖励列表响应: %s", string(resp.Body()))
+	logBody(ctx, "📔 签到奖励列表响应", resp.Body())
 	var result models.SignInRewards
 	if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
-		log.Printf("❌ 解析响应失败: %v", err)
+		slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err)
 		return nil, fmt.Errorf("解析响应失败: %w", err)
 	}
 
+	if result.Code == 10000 {
+		if err := s.history.Record(month, result.Data, nil); err != nil {
+			slog.WarnContext(ctx, "⚠️ 保存奖励历史失败", "month", month, "error", err)
+		}
+	}
+
 	return &result, nil
 }
 
-func (s *FF14Service) GetSignReward(id int) ([]byte, error) {
-	log.Printf("🎁 领取签到奖励... 奖励id %d", id)
+// GetSignReward 领取指定 id 的签到奖励，month 格式为 2006-01，为空时取当月
+func (s *FF14Service) GetSignReward(ctx context.Context, id int, month string) ([]byte, error) {
+	month, err := NormalizeMonth(month)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "🎁 领取签到奖励", "id", id, "month", month)
 
 	if !s.state.HasCookie() {
-		log.Println("⚠️ Cookie未配置")
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
 		return nil, fmt.Errorf("cookie未配置")
 	}
 
-	req := s.setCommonHeaders(s.client.R())
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 	reqBody := map[string]any{
 		"id":    id,
-		"month": time.Now().Format("2006-01"),
+		"month": month,
 	}
 	resp, err := req.
 		SetBody(reqBody).
-		Post(s.buildURL(config.GetSignRewardPath))
+		Post(s.buildURL(s.state.Settings().GetSignRewardPath))
 
 	if err != nil {
-		log.Printf("❌ 请求失败: %v", err)
+		metrics.ObserveResult(metrics.RewardClaims, false)
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 
 	body := resp.Body()
-	log.Printf("🎁 领取签到奖励响应: %s", string(body))
+	logBody(ctx, "🎁 领取签到奖励响应", body)
+
+	code, ok := parseUpstreamCode(body)
+	metrics.ObserveResult(metrics.RewardClaims, ok && code == 10000)
 
 	return body, nil
 }
 
-// SearchUser 搜索用户
-func (s *FF14Service) SearchUser(name string, groupName string) (*models.UserInfo, error) {
+// SearchUser 搜索用户，结果按 角色名+服务器 缓存 search_cache_ttl，refresh 为 true 时跳过缓存
+func (s *FF14Service) SearchUser(ctx context.Context, name string, groupName string, refresh bool) (*models.UserInfo, error) {
+	key := "search:" + name + "@" + groupName
+	return cache.Do(ctx, s.cache, key, s.state.Settings().SearchCacheTTL.D(), refresh, func(ctx context.Context) (*models.UserInfo, error) {
+		return s.searchUser(ctx, name, groupName)
+	})
+}
+
+// LookupProfile 按 UUID 查询用户信息，结果缓存 profile_cache_ttl，refresh 为 true 时跳过缓存
+func (s *FF14Service) LookupProfile(ctx context.Context, uuid string, refresh bool) (*models.UserInfoResp, error) {
+	if uuid == "" {
+		return nil, fmt.Errorf("uuid不能为空")
+	}
+	return cache.Do(ctx, s.cache, "profile:"+uuid, s.state.Settings().ProfileCacheTTL.D(), refresh, func(ctx context.Context) (*models.UserInfoResp, error) {
+		resp, err := s.GetUserInfo(ctx, uuid)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Code != 10000 {
+			return nil, fmt.Errorf("查询用户信息失败: %d %s", resp.Code, resp.Msg)
+		}
+		return resp, nil
+	})
+}
+
+// CacheStats 获取查询缓存统计
+func (s *FF14Service) CacheStats() models.CacheStats {
+	return s.cache.Stats()
+}
+
+// searchUser 逐页搜索用户
+func (s *FF14Service) searchUser(ctx context.Context, name string, groupName string) (*models.UserInfo, error) {
 	areaName := GetAreaName(groupName)
 
-	log.Printf("🔍 开始搜索用户: %s", name)
+	slog.InfoContext(ctx, "🔍 开始搜索用户", "name", name, "server", groupName)
 
 	if !s.state.HasCookie() {
 		return nil, fmt.Errorf("cookie未配置")
 	}
 
 	for page := 1; page <= 30; page++ {
-		req := s.setCommonHeaders(s.client.R())
+		if page > 1 {
+			if err := s.pace(ctx); err != nil {
+				return nil, err
+			}
+		}
+		req := s.setCommonHeaders(s.client.R().SetContext(ctx))
 
 		resp, err := req.
 			SetQueryParams(map[string]string{
@@ -302,16 +391,16 @@ func (s *FF14Service) SearchUser(name string, groupName string) (*models.UserInf
 				"limit":    "60",
 				"page":     strconv.Itoa(page),
 			}).
-			Get(s.buildURL(config.SearchUserPath))
+			Get(s.buildURL(s.state.Settings().SearchUserPath))
 
 		if err != nil {
-			log.Printf("❌ 请求失败: %v", err)
+			slog.ErrorContext(ctx, "❌ 请求失败", "error", err, "page", page)
 			return nil, fmt.Errorf("请求失败: %w", err)
 		}
 
 		var result models.SearchResponse
 		if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
-			log.Printf("❌ 解析响应失败: %v", err)
+			slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err, "page", page)
 			return nil, fmt.Errorf("解析响应失败: %w", err)
 		}
 		if result.Code != 10000 {
@@ -354,7 +443,7 @@ func (s *FF14Service) parseUserInfo(user models.UserProfile) *models.UserInfo {
 }
 
 // saveResponse 保存响应数据
-func (s *FF14Service) saveResponse(body []byte) error {
+func (s *FF14Service) saveResponse(ctx context.Context, body []byte) error {
 	var data []byte
 
 	// 尝试格式化 JSON
@@ -370,13 +459,13 @@ func (s *FF14Service) saveResponse(body []byte) error {
 		}
 	}
 
-	log.Printf("📄 响应内容预览:\n%s", truncateString(string(data), 500))
+	logBody(ctx, "📄 响应内容预览", data)
 
 	// 保存到内存
 	s.state.SetResponseData(data)
 
 	// 保存到文件
-	return os.WriteFile(config.OutputFile, data, 0644)
+	return store.WriteFile(s.state.Settings().OutputFile(), data, 0644, store.DefaultBackups)
 }
 
 func (s *FF14Service) saveBaseInfo(infoResp *models.UserInfoResp) error {
@@ -385,7 +474,7 @@ func (s *FF14Service) saveBaseInfo(infoResp *models.UserInfoResp) error {
 		return fmt.Errorf("编码infoResp失败: %w", err)
 	}
 	s.state.SetResponseData(b)
-	return os.WriteFile(config.OutputFile, b, 0644)
+	return store.WriteFile(s.state.Settings().OutputFile(), b, 0644, store.DefaultBackups)
 }
 
 // ParseFFInfo 获取处理后的 FF 信息
@@ -394,7 +483,7 @@ func (s *FF14Service) ParseFFInfo() (*models.FFInfoData, error) {
 
 	if len(data) == 0 {
 		// 尝试从文件读取
-		fileData, err := os.ReadFile(config.OutputFile)
+		fileData, err := store.ReadFile(s.state.Settings().OutputFile(), store.ValidJSON, store.DefaultBackups)
 		if err != nil {
 			return nil, fmt.Errorf("数据尚未获取")
 		}
@@ -405,6 +494,9 @@ func (s *FF14Service) ParseFFInfo() (*models.FFInfoData, error) {
 	if err := sonic.Unmarshal(data, &apiResp); err != nil {
 		return nil, fmt.Errorf("数据解析失败: %w", err)
 	}
+	if len(apiResp.Data.CharacterDetail) == 0 {
+		return nil, fmt.Errorf("数据中没有角色详情")
+	}
 
 	playTimeMinutes := ParsePlayTimeToMinutes(apiResp.Data.CharacterDetail[0].PlayTime)
 
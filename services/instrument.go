@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-resty/resty/v2"
+
+	"llmaget/metrics"
+)
+
+// upstreamCode 上游响应中的业务码
+type upstreamCode struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// instrumentClient 为 resty 客户端挂载统一的指标采集
+func instrumentClient(client *resty.Client) {
+	client.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		code := "-"
+		if c, ok := parseUpstreamCode(resp.Body()); ok {
+			code = strconv.Itoa(c)
+		}
+		metrics.ObserveUpstream(requestPath(resp.Request), resp.Request.Method, resp.StatusCode(), code, resp.Time())
+		return nil
+	})
+
+	// 网络错误不会经过 OnAfterResponse，在这里单独记录
+	client.OnError(func(req *resty.Request, err error) {
+		if _, ok := err.(*resty.ResponseError); ok {
+			return
+		}
+		// 熔断时请求未发出，不计入上游请求
+		if errors.Is(err, ErrCircuitOpen) {
+			return
+		}
+		elapsed := time.Duration(0)
+		if !req.Time.IsZero() {
+			elapsed = time.Since(req.Time)
+		}
+		metrics.ObserveUpstream(requestPath(req), req.Method, 0, "-", elapsed)
+	})
+}
+
+// requestPath 提取请求路径作为指标标签
+func requestPath(req *resty.Request) string {
+	if req.RawRequest != nil && req.RawRequest.URL != nil {
+		return req.RawRequest.URL.Path
+	}
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return "unknown"
+	}
+	return u.Path
+}
+
+// parseUpstreamCode 解析上游响应中的业务码
+func parseUpstreamCode(body []byte) (int, bool) {
+	var c upstreamCode
+	if err := sonic.Unmarshal(body, &c); err != nil {
+		return 0, false
+	}
+	return c.Code, true
+}
+
+// parseUpstreamStatus 解析上游响应中的业务码与提示信息
+func parseUpstreamStatus(body []byte) (upstreamCode, bool) {
+	var c upstreamCode
+	if err := sonic.Unmarshal(body, &c); err != nil {
+		return c, false
+	}
+	return c, true
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/bytedance/sonic"
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+
+	"llmaget/config"
+	"llmaget/models"
+)
+
+// missionActionLimit 单个任务每次最多自动执行的动作数，避免任务目标异常时大量发帖互动
+const missionActionLimit = 10
+
+// missionActionNames 动作的中文名称，用于结果汇总
+var missionActionNames = map[string]string{
+	config.MissionBrowse:  "浏览",
+	config.MissionLike:    "点赞",
+	config.MissionComment: "评论",
+}
+
+// MissionList 获取每日社区任务及完成情况
+func (s *FF14Service) MissionList(ctx context.Context) (*models.MissionList, error) {
+	slog.InfoContext(ctx, "📋 获取社区任务列表")
+
+	if !s.state.HasCookie() {
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
+		return nil, fmt.Errorf("cookie未配置")
+	}
+
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+	resp, err := req.
+		SetQueryParam("tempsuid", uuid.New().String()).
+		Get(s.buildURL(s.state.Settings().MissionListPath))
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+
+	logBody(ctx, "📋 社区任务列表响应", resp.Body())
+	var result models.MissionList
+	if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
+		slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// DoMissions 完成已开启动作的社区任务并领取任务积分
+//
+// 只自动执行 mission_actions 中开启的动作，动作之间按 bulk_delay_min/max 间隔。
+// 结果与 SignAndGetSignReward 相同按状态列出任务名称：success 本次领取成功、fail 动作或领取失败、
+// claimed 之前已领取、unavailable 未完成且未开启对应动作；actions 为本次执行的动作。
+func (s *FF14Service) DoMissions(ctx context.Context) ([]byte, error) {
+	respMap, err := s.doMissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sonic.Marshal(respMap)
+}
+
+// doMissions 执行社区任务并领取积分，返回各状态的任务名称
+func (s *FF14Service) doMissions(ctx context.Context) (map[string][]string, error) {
+	list, err := s.missionList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := s.state.Settings().MissionActionSet()
+	if err != nil {
+		return nil, err
+	}
+
+	respMap := map[string][]string{
+		"unavailable": {},
+		"claimed":     {},
+		"success":     {},
+		"fail":        {},
+		"actions":     {},
+	}
+	failed := make(map[int]bool)
+	run := &missionRun{svc: s, next: make(map[string]int)}
+
+	acted := false
+	for _, m := range list.Data {
+		if m.Status != models.MissionIncomplete || !actions[m.Type] {
+			continue
+		}
+		done, err := run.complete(ctx, m)
+		respMap["actions"] = append(respMap["actions"], done...)
+		acted = acted || len(done) > 0
+		if err != nil {
+			failed[m.ID] = true
+			respMap["fail"] = append(respMap["fail"], m.Name)
+			slog.WarnContext(ctx, "⚠️ 社区任务未完成", "task", m.Name, "error", err)
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	// 执行过动作后重新获取任务状态
+	if acted {
+		if list, err = s.missionList(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, m := range list.Data {
+		switch m.Status {
+		case models.MissionCompleted:
+			if err := s.claimMission(ctx, m); err != nil {
+				respMap["fail"] = append(respMap["fail"], m.Name)
+				slog.WarnContext(ctx, "⚠️ 任务积分领取失败", "task", m.Name, "error", err)
+				continue
+			}
+			respMap["success"] = append(respMap["success"], m.Name)
+			slog.InfoContext(ctx, "✅ 任务积分领取成功", "task", m.Name, "points", m.Points)
+		case models.MissionClaimed:
+			respMap["claimed"] = append(respMap["claimed"], m.Name)
+		default:
+			if !failed[m.ID] {
+				respMap["unavailable"] = append(respMap["unavailable"], m.Name)
+			}
+		}
+	}
+	slog.InfoContext(ctx, "社区任务处理完成", "actions", len(respMap["actions"]), "claimed", len(respMap["success"]))
+	return respMap, nil
+}
+
+// missionList 获取任务列表并校验业务码
+func (s *FF14Service) missionList(ctx context.Context) (*models.MissionList, error) {
+	list, err := s.MissionList(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if list.Code != 10000 {
+		return nil, fmt.Errorf("获取社区任务列表失败: %d %s", list.Code, list.Msg)
+	}
+	return list, nil
+}
+
+// claimMission 领取已完成任务的积分
+func (s *FF14Service) claimMission(ctx context.Context, m models.Mission) error {
+	if err := s.pace(ctx); err != nil {
+		return err
+	}
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+	resp, err := req.
+		SetBody(map[string]any{"id": m.ID}).
+		Post(s.buildURL(s.state.Settings().MissionClaimPath))
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	logBody(ctx, "🎁 任务积分领取响应", resp.Body())
+	if code, ok := parseUpstreamCode(resp.Body()); !ok || code != 10000 {
+		return fmt.Errorf("业务码 %d", code)
+	}
+	return nil
+}
+
+// missionRun 一次社区任务执行中共享的帖子列表与节奏
+type missionRun struct {
+	svc    *FF14Service
+	posts  []models.Post
+	loaded bool
+	// next 各动作下一个使用的帖子下标，同一动作不重复作用于同一帖子
+	next map[string]int
+	sent int
+}
+
+// complete 执行任务剩余次数的动作，返回已执行的动作描述
+func (r *missionRun) complete(ctx context.Context, m models.Mission) ([]string, error) {
+	if err := r.load(ctx); err != nil {
+		return nil, err
+	}
+
+	var done []string
+	n := min(m.Remaining(), missionActionLimit)
+	for range n {
+		post, ok := r.pick(m.Type)
+		if !ok {
+			return done, fmt.Errorf("没有可用于%s的帖子", missionActionNames[m.Type])
+		}
+		if r.sent > 0 {
+			if err := r.svc.pace(ctx); err != nil {
+				return done, err
+			}
+		}
+		r.sent++
+		if err := r.svc.postAction(ctx, m.Type, post); err != nil {
+			return done, fmt.Errorf("%s %s: %w", missionActionNames[m.Type], post.Title, err)
+		}
+		done = append(done, missionActionNames[m.Type]+" "+post.Title)
+		slog.InfoContext(ctx, "👍 社区任务动作完成", "task", m.Name, "action", m.Type, "post", post.ID)
+	}
+	return done, nil
+}
+
+// load 首次使用时获取帖子列表
+func (r *missionRun) load(ctx context.Context) error {
+	if r.loaded {
+		return nil
+	}
+	posts, err := r.svc.postsList(ctx)
+	if err != nil {
+		return err
+	}
+	r.posts, r.loaded = posts, true
+	return nil
+}
+
+// pick 为动作选择下一个帖子，点赞跳过已点赞的帖子
+func (r *missionRun) pick(action string) (models.Post, bool) {
+	for i := r.next[action]; i < len(r.posts); i++ {
+		p := r.posts[i]
+		if action == config.MissionLike && p.IsLike != 0 {
+			continue
+		}
+		r.next[action] = i + 1
+		return p, true
+	}
+	r.next[action] = len(r.posts)
+	return models.Post{}, false
+}
+
+// postsList 获取首页帖子列表
+func (s *FF14Service) postsList(ctx context.Context) ([]models.Post, error) {
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+	resp, err := req.
+		SetQueryParams(map[string]string{
+			"type":     "1",
+			"page":     "1",
+			"limit":    "20",
+			"tempsuid": uuid.New().String(),
+		}).
+		Get(s.buildURL(s.state.Settings().PostsListPath))
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	logBody(ctx, "📰 帖子列表响应", resp.Body())
+
+	var result models.PostsList
+	if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("解析帖子列表失败: %w", err)
+	}
+	if result.Code != 10000 {
+		return nil, fmt.Errorf("获取帖子列表失败: %d %s", result.Code, result.Msg)
+	}
+	return result.Data.Rows, nil
+}
+
+// postAction 对帖子执行一次浏览、点赞或评论
+func (s *FF14Service) postAction(ctx context.Context, action string, post models.Post) error {
+	st := s.state.Settings()
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+
+	var resp *resty.Response
+	var err error
+	switch action {
+	case config.MissionBrowse:
+		resp, err = req.
+			SetQueryParams(map[string]string{
+				"id":       strconv.Itoa(post.ID),
+				"tempsuid": uuid.New().String(),
+			}).
+			Get(s.buildURL(st.PostDetailPath))
+	case config.MissionLike:
+		resp, err = req.
+			SetBody(map[string]any{"id": post.ID, "type": 1}).
+			Post(s.buildURL(st.PostLikePath))
+	case config.MissionComment:
+		resp, err = req.
+			SetBody(map[string]any{
+				"posts_id":    post.ID,
+				"content":     st.MissionComment,
+				"parent_id":   0,
+				"root_parent": 0,
+				"comment_pic": "",
+			}).
+			Post(s.buildURL(st.PostCommentPath))
+	default:
+		return fmt.Errorf("不支持的动作 %s", action)
+	}
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+
+	logBody(ctx, "社区任务动作响应", resp.Body())
+	if code, ok := parseUpstreamCode(resp.Body()); !ok || code != 10000 {
+		return fmt.Errorf("业务码 %d", code)
+	}
+	return nil
+}
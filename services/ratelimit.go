@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"golang.org/x/time/rate"
+
+	"llmaget/config"
+	"llmaget/metrics"
+)
+
+// rateLimiter 上游请求限速器，每个主机与每个账号各一个令牌桶
+//
+// 速率每次取自当前配置，热加载修改 upstream_qps/account_qps 后立即生效。
+type rateLimiter struct {
+	state *config.AppState
+
+	mu       sync.Mutex
+	hosts    map[string]*rate.Limiter
+	accounts map[string]*rate.Limiter
+	// paused 主机被上游限流后的暂停截止时间
+	paused map[string]time.Time
+}
+
+// newRateLimiter 创建限速器
+func newRateLimiter(state *config.AppState) *rateLimiter {
+	return &rateLimiter{
+		state:    state,
+		hosts:    make(map[string]*rate.Limiter),
+		accounts: make(map[string]*rate.Limiter),
+		paused:   make(map[string]time.Time),
+	}
+}
+
+// bucket 获取 key 对应的令牌桶，速率变化时同步更新（调用方需持有锁）
+func bucket(m map[string]*rate.Limiter, key string, qps float64) *rate.Limiter {
+	limit := rate.Limit(qps)
+	burst := max(1, int(math.Ceil(qps)))
+
+	lim, ok := m[key]
+	if !ok {
+		lim = rate.NewLimiter(limit, burst)
+		m[key] = lim
+		return lim
+	}
+	if lim.Limit() != limit {
+		lim.SetLimit(limit)
+		lim.SetBurst(burst)
+	}
+	return lim
+}
+
+// Wait 等待主机暂停结束，并依次取得主机与账号的令牌
+func (l *rateLimiter) Wait(ctx context.Context, host, account string) error {
+	st := l.state.Settings()
+	start := time.Now()
+
+	l.mu.Lock()
+	until := l.paused[host]
+	hostLim := bucket(l.hosts, host, st.UpstreamQPS)
+	accountLim := bucket(l.accounts, account, st.AccountQPS)
+	l.mu.Unlock()
+
+	if d := time.Until(until); d > 0 {
+		slog.WarnContext(ctx, "⏳ 上游限流中，暂停请求", "host", host, "wait", d.Round(time.Millisecond))
+		if err := sleepContext(ctx, d); err != nil {
+			return err
+		}
+	}
+	if err := hostLim.Wait(ctx); err != nil {
+		return err
+	}
+	if err := accountLim.Wait(ctx); err != nil {
+		return err
+	}
+
+	metrics.UpstreamLimiterWait.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// Pause 暂停主机的请求 d 时长，已有更晚的暂停时保持不变
+func (l *rateLimiter) Pause(host string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if until := time.Now().Add(d); until.After(l.paused[host]) {
+		l.paused[host] = until
+	}
+}
+
+// limitClient 为 resty 客户端挂载限速与限流退避
+//
+// 限速在每次尝试（含重试）发出前生效；上游返回 429 或 throttle_codes 中的业务码时，
+// 按 Retry-After（缺省为 throttle_backoff）暂停该主机的所有请求并重试。
+func limitClient(client *resty.Client, limiter *rateLimiter, state *config.AppState) {
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		// 当前仅支持单账号，账号桶以默认账号为键
+		return limiter.Wait(req.Context(), requestHost(req.URL), config.DefaultAccount)
+	})
+
+	client.OnAfterResponse(func(_ *resty.Client, resp *resty.Response) error {
+		d, throttled := throttleDelay(resp, state.Settings())
+		if !throttled {
+			return nil
+		}
+		host := requestHost(resp.Request.URL)
+		metrics.UpstreamThrottled.WithLabelValues(host).Inc()
+		slog.WarnContext(resp.Request.Context(), "🚦 上游限流，暂停请求", "host", host, "status", resp.StatusCode(), "backoff", d)
+		limiter.Pause(host, d)
+		return nil
+	})
+
+	// 设置重试条件后 resty 不再默认重试网络错误，这里保留该行为；
+	// resp 为空说明请求在发出前就被拦截（如 ctx 取消），不重试
+	client.AddRetryCondition(func(resp *resty.Response, err error) bool {
+		if resp == nil {
+			return false
+		}
+		if err != nil {
+			return true
+		}
+		_, throttled := throttleDelay(resp, state.Settings())
+		return throttled
+	})
+}
+
+// throttleDelay 判断响应是否表示限流，并返回需要退避的时长
+func throttleDelay(resp *resty.Response, st config.Settings) (time.Duration, bool) {
+	throttled := resp.StatusCode() == http.StatusTooManyRequests
+	if !throttled {
+		codes, _ := st.ThrottleCodeSet()
+		if len(codes) > 0 {
+			code, ok := parseUpstreamCode(resp.Body())
+			throttled = ok && codes[code]
+		}
+	}
+	if !throttled {
+		return 0, false
+	}
+
+	if d, ok := parseRetryAfter(resp.Header().Get("Retry-After")); ok {
+		return d, true
+	}
+	return st.ThrottleBackoff.D(), true
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数与 HTTP 日期两种形式
+func parseRetryAfter(v string) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t)), true
+	}
+	return 0, false
+}
+
+// requestHost 提取请求的主机名
+func requestHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "unknown"
+	}
+	return u.Host
+}
+
+// pace 批量操作的请求间隔，在 bulk_delay_min 与 bulk_delay_max 之间随机，模拟人工操作节奏
+func (s *FF14Service) pace(ctx context.Context) error {
+	st := s.state.Settings()
+	d := st.BulkDelayMin.D()
+	if span := st.BulkDelayMax.D() - d; span > 0 {
+		d += rand.N(span)
+	}
+	return sleepContext(ctx, d)
+}
+
+// sleepContext 等待 d 时长，ctx 结束时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
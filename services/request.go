@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Request 以当前账号的 Cookie 与通用请求头调用石之家接口，path 为以 / 开头的接口路径
+//
+// 请求与内置接口共用限速、熔断与指标采集，供工作流等调用未内置接口的模块使用。
+// body 不为空时按 JSON 发送；返回 HTTP 状态码与响应内容。
+func (s *FF14Service) Request(ctx context.Context, method, path string, query map[string]string, body []byte) (int, []byte, error) {
+	if !strings.HasPrefix(path, "/") {
+		return 0, nil, fmt.Errorf("接口路径需以 / 开头: %s", path)
+	}
+	if !s.state.HasCookie() {
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
+		return 0, nil, fmt.Errorf("cookie未配置")
+	}
+
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx)).SetQueryParams(query)
+	if len(body) > 0 {
+		req.SetHeader("Content-Type", "application/json").SetBody(body)
+	}
+
+	resp, err := req.Execute(strings.ToUpper(method), s.buildURL(path))
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 请求失败", "method", method, "path", path, "error", err)
+		return 0, nil, fmt.Errorf("请求失败: %w", err)
+	}
+	logBody(ctx, "📥 "+method+" "+path, resp.Body())
+	return resp.StatusCode(), resp.Body(), nil
+}
+
+// Pace 批量操作的请求间隔，在 bulk_delay_min 与 bulk_delay_max 之间随机
+func (s *FF14Service) Pace(ctx context.Context) error {
+	return s.pace(ctx)
+}
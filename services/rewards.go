@@ -0,0 +1,271 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+	"llmaget/models"
+	"llmaget/store"
+)
+
+// MonthLayout 奖励月份格式
+const MonthLayout = "2006-01"
+
+// ErrInvalidMonth 月份参数格式错误
+var ErrInvalidMonth = errors.New("月份格式应为 2006-01")
+
+// CurrentMonth 业务时区下的当前奖励月份
+func CurrentMonth() string {
+	return clock.Now().Format(MonthLayout)
+}
+
+// PreviousMonth 返回 month 的上一个月
+func PreviousMonth(month string) string {
+	t, err := time.Parse(MonthLayout, month)
+	if err != nil {
+		return ""
+	}
+	return t.AddDate(0, -1, 0).Format(MonthLayout)
+}
+
+// NormalizeMonth 校验月份参数，为空时返回当月
+func NormalizeMonth(month string) (string, error) {
+	if month == "" {
+		return CurrentMonth(), nil
+	}
+	if _, err := time.Parse(MonthLayout, month); err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidMonth, month)
+	}
+	return month, nil
+}
+
+// RewardHistory 按月保存的签到奖励表
+type RewardHistory struct {
+	mu   sync.Mutex
+	file string
+}
+
+// NewRewardHistory 创建奖励历史，数据保存在 file 中
+func NewRewardHistory(file string) *RewardHistory {
+	return &RewardHistory{file: file}
+}
+
+// loadLocked 读取全部月份（调用方需持有锁）
+func (h *RewardHistory) loadLocked() (map[string]*models.RewardMonth, error) {
+	months := make(map[string]*models.RewardMonth)
+	_, err := store.ReadFile(h.file, func(data []byte) error {
+		clear(months)
+		return sonic.Unmarshal(data, &months)
+	}, store.DefaultBackups)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("读取奖励历史失败: %w", err)
+	}
+	return months, nil
+}
+
+// Record 记录某月的奖励表，claimed 为本次领取成功的奖励 id 与时间
+//
+// 之前记录的领取时间会被保留。
+func (h *RewardHistory) Record(month string, rewards []models.SignReward, claimed map[int]time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	months, err := h.loadLocked()
+	if err != nil {
+		return err
+	}
+
+	prev := make(map[int]*time.Time)
+	if old, ok := months[month]; ok {
+		for _, r := range old.Rewards {
+			prev[r.ID] = r.ClaimedAt
+		}
+	}
+
+	records := make([]models.RewardRecord, 0, len(rewards))
+	for _, r := range rewards {
+		rec := models.RewardRecord{SignReward: r, ClaimedAt: prev[r.ID]}
+		if at, ok := claimed[r.ID]; ok {
+			rec.IsGet = 1
+			rec.ClaimedAt = &at
+		}
+		records = append(records, rec)
+	}
+	months[month] = &models.RewardMonth{
+		Month:     month,
+		UpdatedAt: clock.Now(),
+		Rewards:   records,
+	}
+
+	data, err := sonic.MarshalIndent(months, "", "  ")
+	if err != nil {
+		return fmt.Errorf("编码奖励历史失败: %w", err)
+	}
+	return store.WriteFile(h.file, data, 0644, store.DefaultBackups)
+}
+
+// Month 获取某月的奖励表
+func (h *RewardHistory) Month(month string) (*models.RewardMonth, bool, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	months, err := h.loadLocked()
+	if err != nil {
+		return nil, false, err
+	}
+	m, ok := months[month]
+	return m, ok, nil
+}
+
+// List 获取全部月份的奖励表，按月份倒序
+func (h *RewardHistory) List() ([]models.RewardMonth, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	months, err := h.loadLocked()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]models.RewardMonth, 0, len(months))
+	for _, m := range months {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Month > list[j].Month
+	})
+	return list, nil
+}
+
+// RewardHistory 获取奖励历史
+func (s *FF14Service) RewardHistory() *RewardHistory {
+	return s.history
+}
+
+// ClaimRewards 领取指定月份所有可领取的奖励，month 为空时取当月
+func (s *FF14Service) ClaimRewards(ctx context.Context, month string) ([]byte, error) {
+	respMap, err := s.claimRewards(ctx, month)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := sonic.Marshal(respMap)
+	if err != nil {
+		slog.ErrorContext(ctx, "map转换json失败", "error", err)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// claimRewards 领取奖励并记录奖励表，返回各状态的奖励名称
+//
+// 单个奖励领取失败时记入 fail 并继续；任务被取消或上游熔断时停止领取，已领取的奖励仍会记录后再返回错误。
+func (s *FF14Service) claimRewards(ctx context.Context, month string) (map[string][]string, error) {
+	month, err := NormalizeMonth(month)
+	if err != nil {
+		return nil, err
+	}
+
+	rewardsBody, err := s.SignRewardList(ctx, month)
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 获取奖励列表时发生错误", "error", err)
+		return nil, err
+	}
+	if rewardsBody.Code != 10000 {
+		slog.ErrorContext(ctx, "❌ 获取奖励列表失败", "code", rewardsBody.Code, "msg", rewardsBody.Msg)
+		return nil, fmt.Errorf("获取奖励列表失败: %d %s", rewardsBody.Code, rewardsBody.Msg)
+	}
+
+	respMap := map[string][]string{
+		"unavailable": {},
+		"available":   {},
+		"claimed":     {},
+		"success":     {},
+		"fail":        {},
+	}
+	claimed := make(map[int]time.Time)
+	// aborted 任务被取消或已熔断，不再继续领取，但本轮已领取的奖励仍需记录
+	var aborted error
+
+	for _, reward := range rewardsBody.Data {
+		if aborted != nil {
+			break
+		}
+		if reward.IsGet == 0 {
+			respMap["available"] = append(respMap["available"], reward.ItemName)
+			slog.InfoContext(ctx, "奖励可领取", "item", reward.ItemName, "month", month)
+			resp, err := s.GetSignReward(ctx, reward.ID, month)
+			if err != nil {
+				respMap["fail"] = append(respMap["fail"], reward.ItemName)
+				slog.ErrorContext(ctx, "❌ 奖励领取失败", "item", reward.ItemName, "error", err)
+				logBody(ctx, "奖励领取失败响应", resp)
+				if ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+					aborted = err
+				}
+				continue
+			}
+			if code, ok := parseUpstreamCode(resp); !ok || code != 10000 {
+				respMap["fail"] = append(respMap["fail"], reward.ItemName)
+				slog.WarnContext(ctx, "⚠️ 奖励领取未成功", "item", reward.ItemName, "code", code)
+				logBody(ctx, "奖励领取失败响应", resp)
+				continue
+			}
+			respMap["success"] = append(respMap["success"], reward.ItemName)
+			claimed[reward.ID] = clock.Now()
+			slog.InfoContext(ctx, "✅ 奖励领取成功", "item", reward.ItemName)
+			logBody(ctx, "奖励领取响应", resp)
+		} else if reward.IsGet == 1 {
+			respMap["claimed"] = append(respMap["claimed"], reward.ItemName)
+			slog.InfoContext(ctx, "奖励已领取，跳过", "item", reward.ItemName)
+		} else {
+			respMap["unavailable"] = append(respMap["unavailable"], reward.ItemName)
+			slog.InfoContext(ctx, "奖励暂未达到领取条件，跳过", "item", reward.ItemName)
+		}
+	}
+
+	if len(claimed) > 0 {
+		if err := s.history.Record(month, rewardsBody.Data, claimed); err != nil {
+			slog.WarnContext(ctx, "⚠️ 保存奖励历史失败", "month", month, "error", err)
+		}
+	}
+	if aborted != nil {
+		return nil, aborted
+	}
+	slog.InfoContext(ctx, "奖励领取处理完成", "month", month)
+	return respMap, nil
+}
+
+// SweepRewards 月末扫尾，领取当月仍可领取的奖励；每月 1 日同时补领上月奖励
+func (s *FF14Service) SweepRewards(ctx context.Context) ([]byte, error) {
+	now := clock.Now()
+	months := []string{now.Format(MonthLayout)}
+	if now.Day() == 1 {
+		months = append([]string{PreviousMonth(months[0])}, months...)
+	}
+
+	result := make(map[string]map[string][]string, len(months))
+	var errs []error
+	for _, month := range months {
+		slog.InfoContext(ctx, "🧹 扫尾领取奖励", "month", month)
+		respMap, err := s.claimRewards(ctx, month)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", month, err))
+			continue
+		}
+		result[month] = respMap
+	}
+	if len(result) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	if len(errs) > 0 {
+		slog.WarnContext(ctx, "⚠️ 部分月份扫尾失败", "error", errors.Join(errs...))
+	}
+	return sonic.Marshal(result)
+}
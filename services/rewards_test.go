@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/jobs"
+)
+
+func TestNormalizeMonth(t *testing.T) {
+	tests := []struct {
+		name  string
+		now   time.Time
+		month string
+		want  string
+		err   error
+	}{
+		{"指定月份", time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC), "2025-12", "2025-12", nil},
+		{"上海月初 UTC 仍在上月", time.Date(2026, 1, 31, 16, 0, 0, 0, time.UTC), "", "2026-02", nil},
+		{"上海月末最后一秒", time.Date(2026, 1, 31, 15, 59, 59, 0, time.UTC), "", "2026-01", nil},
+		{"跨年", time.Date(2025, 12, 31, 16, 0, 0, 0, time.UTC), "", "2026-01", nil},
+		{"格式错误", time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC), "2026-5", "", ErrInvalidMonth},
+		{"月份越界", time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC), "2026-13", "", ErrInvalidMonth},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer clock.Set(clock.Fixed(tt.now))()
+			got, err := NormalizeMonth(tt.month)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("NormalizeMonth(%q) error = %v, want %v", tt.month, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeMonth(%q) = %q, want %q", tt.month, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousMonth(t *testing.T) {
+	tests := map[string]string{
+		"2026-03": "2026-02",
+		"2026-01": "2025-12",
+		"bad":     "",
+	}
+	for in, want := range tests {
+		if got := PreviousMonth(in); got != want {
+			t.Errorf("PreviousMonth(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// upstreamStub 启动按路径返回固定响应的石之家桩服务，并加载指向它的配置
+func upstreamStub(t *testing.T, bodies map[string]string) *FF14Service {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		for path, body := range bodies {
+			if strings.HasSuffix(r.URL.Path, path) {
+				io.WriteString(w, body)
+				return
+			}
+		}
+		io.WriteString(w, `{"code":10000,"msg":"ok","data":{}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	data := `{"cookie":"tok","settings":{"scheme":"http","base_url":"` + strings.TrimPrefix(srv.URL, "http://") +
+		`","data_dir":"` + filepath.ToSlash(dir) + `","bulk_delay_min":"1ms","bulk_delay_max":"2ms"}}`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := config.ConfigFile
+	config.ConfigFile = file
+	t.Cleanup(func() { config.ConfigFile = old })
+	if err := config.GetState().Load(); err != nil {
+		t.Fatalf("加载配置失败: %v", err)
+	}
+	return NewFF14Service()
+}
+
+func TestClaimJobsFailOnUpstreamCode(t *testing.T) {
+	const (
+		signOK      = `{"code":10000,"msg":"ok","data":{}}`
+		signRepeat  = `{"code":10001,"msg":"今日已签到"}`
+		signExpired = `{"code":10103,"msg":"请先登录"}`
+		listOK      = `{"code":10000,"msg":"ok","data":[{"id":1,"item_name":"A","is_get":1}]}`
+		listExpired = `{"code":10103,"msg":"请先登录"}`
+	)
+	tests := []struct {
+		name   string
+		kind   string
+		sign   string
+		list   string
+		status jobs.Status
+		err    string
+	}{
+		{"签到并领取成功", jobs.KindSignAndClaim, signOK, listOK, jobs.StatusSucceeded, ""},
+		{"今日已签到仍领取奖励", jobs.KindSignAndClaim, signRepeat, listOK, jobs.StatusSucceeded, ""},
+		{"签到登录失效", jobs.KindSignAndClaim, signExpired, listOK, jobs.StatusFailed, "签到失败: 10103"},
+		{"奖励列表登录失效", jobs.KindSignAndClaim, signOK, listExpired, jobs.StatusFailed, "获取奖励列表失败: 10103"},
+		{"月末扫尾登录失效", jobs.KindRewardSweep, signOK, listExpired, jobs.StatusFailed, "获取奖励列表失败: 10103"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := upstreamStub(t, map[string]string{
+				"/sign/signIn":         tt.sign,
+				"/sign/signRewardList": tt.list,
+			})
+			m := jobs.NewManager(filepath.Join(t.TempDir(), "jobs.json"))
+			m.Register(jobs.KindSignAndClaim, func(ctx context.Context) (any, error) { return svc.SignAndGetSignReward(ctx) })
+			m.Register(jobs.KindRewardSweep, func(ctx context.Context) (any, error) { return svc.SweepRewards(ctx) })
+			m.Start(1)
+			defer m.Shutdown(context.Background())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			job, _, err := m.Submit(ctx, tt.kind, "default", jobs.TriggerManual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			job, err = m.Wait(ctx, job.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.Status != tt.status || !strings.Contains(job.Error, tt.err) {
+				t.Errorf("任务 = %s %q, want %s %q", job.Status, job.Error, tt.status, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"llmaget/models"
+	"llmaget/store"
+)
+
+// PointsBalance 获取社区积分余额
+func (s *FF14Service) PointsBalance(ctx context.Context) (*models.PointsResp, error) {
+	slog.InfoContext(ctx, "💰 获取积分余额")
+
+	if !s.state.HasCookie() {
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
+		return nil, fmt.Errorf("cookie未配置")
+	}
+
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+	resp, err := req.
+		SetQueryParam("tempsuid", uuid.New().String()).
+		Get(s.buildURL(s.state.Settings().PointsPath))
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+
+	logBody(ctx, "💰 积分余额响应", resp.Body())
+	var result models.PointsResp
+	if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
+		slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// ShopItems 获取积分商城商品列表
+func (s *FF14Service) ShopItems(ctx context.Context) (*models.ShopItems, error) {
+	slog.InfoContext(ctx, "🛒 获取积分商城商品")
+
+	if !s.state.HasCookie() {
+		slog.WarnContext(ctx, "⚠️ Cookie未配置")
+		return nil, fmt.Errorf("cookie未配置")
+	}
+
+	req := s.setCommonHeaders(s.client.R().SetContext(ctx))
+	resp, err := req.
+		SetQueryParam("tempsuid", uuid.New().String()).
+		Get(s.buildURL(s.state.Settings().ShopItemsPath))
+	if err != nil {
+		slog.ErrorContext(ctx, "❌ 请求失败", "error", err)
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+
+	logBody(ctx, "🛒 积分商城响应", resp.Body())
+	var result models.ShopItems
+	if err := sonic.Unmarshal(resp.Body(), &result); err != nil {
+		slog.ErrorContext(ctx, "❌ 解析响应失败", "error", err)
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	return &result, nil
+}
+
+// ShopLog 按行追加的积分余额与商城库存记录（JSON Lines）
+type ShopLog struct {
+	mu   sync.Mutex
+	file string
+}
+
+// NewShopLog 创建积分记录，数据保存在 file 中
+func NewShopLog(file string) *ShopLog {
+	return &ShopLog{file: file}
+}
+
+// Append 追加一条记录
+func (l *ShopLog) Append(rec models.ShopRecord) error {
+	line, err := sonic.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("编码积分记录失败: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return store.AppendLine(l.file, line)
+}
+
+// Each 按写入顺序逐条读取记录；无法解析的行会被跳过
+func (l *ShopLog) Each(fn func(models.ShopRecord) error) error {
+	f, err := os.Open(l.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec models.ShopRecord
+		if err := sonic.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			slog.Warn("⚠️ 积分记录损坏，跳过", "file", l.file, "line", lineNo, "error", err)
+			continue
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Last 获取指定账号的最近一条记录，没有记录时返回 nil
+func (l *ShopLog) Last(account string) (*models.ShopRecord, error) {
+	var last *models.ShopRecord
+	err := l.Each(func(rec models.ShopRecord) error {
+		if rec.Account == account {
+			last = &rec
+		}
+		return nil
+	})
+	return last, err
+}
+
+// ShopLog 获取积分与商城库存记录
+func (s *FF14Service) ShopLog() *ShopLog {
+	return s.shop
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/models"
+	"llmaget/store"
+)
+
+// SnapshotLog 按行追加的角色信息快照（JSON Lines）
+type SnapshotLog struct {
+	mu   sync.Mutex
+	file string
+}
+
+// NewSnapshotLog 创建快照记录，数据保存在 file 中
+func NewSnapshotLog(file string) *SnapshotLog {
+	return &SnapshotLog{file: file}
+}
+
+// Append 追加一条快照
+func (l *SnapshotLog) Append(snap models.Snapshot) error {
+	line, err := sonic.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("编码快照失败: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return store.AppendLine(l.file, line)
+}
+
+// Each 按写入顺序逐条读取快照，不会把整个文件读入内存；无法解析的行会被跳过
+func (l *SnapshotLog) Each(fn func(models.Snapshot) error) error {
+	f, err := os.Open(l.file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snap models.Snapshot
+		if err := sonic.Unmarshal(scanner.Bytes(), &snap); err != nil {
+			slog.Warn("⚠️ 快照记录损坏，跳过", "file", l.file, "line", lineNo, "error", err)
+			continue
+		}
+		if err := fn(snap); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// Snapshots 获取快照记录
+func (s *FF14Service) Snapshots() *SnapshotLog {
+	return s.snapshots
+}
+
+// newSnapshot 从用户信息生成快照
+func newSnapshot(info *models.UserInfoResp) models.Snapshot {
+	snap := models.Snapshot{
+		Time:          clock.Now(),
+		Account:       config.DefaultAccount,
+		UUID:          info.Data.UUID,
+		CharacterName: info.Data.CharacterName,
+		AreaName:      info.Data.AreaName,
+		GroupName:     info.Data.GroupName,
+	}
+	if len(info.Data.CharacterDetail) > 0 {
+		snap.PlayTime = info.Data.CharacterDetail[0].PlayTime
+		snap.PlayTimeMinutes = ParsePlayTimeToMinutes(snap.PlayTime)
+	}
+	if len(info.Data.CareerLevel) > 0 {
+		snap.JobLevels = make(map[string]string, len(info.Data.CareerLevel))
+		for _, c := range info.Data.CareerLevel {
+			snap.JobLevels[c.Career] = c.CharacterLevel
+		}
+	}
+	for _, a := range info.Data.AchieveInfo {
+		if a.AchieveName != "" {
+			snap.Achievements = append(snap.Achievements, a.AchieveName)
+		}
+	}
+	return snap
+}
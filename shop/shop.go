@@ -0,0 +1,257 @@
+package shop
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/metrics"
+	"llmaget/models"
+	"llmaget/notify"
+	"llmaget/services"
+)
+
+// 提醒类型
+const (
+	AlertAffordable = "affordable" // 积分足够兑换
+	AlertRestock    = "restock"    // 补货
+)
+
+// Item 商品及其关注与可兑换状态
+type Item struct {
+	models.ShopItem
+	Watched    bool `json:"watched"`
+	Affordable bool `json:"affordable"`
+}
+
+// Alert 关注商品的提醒
+type Alert struct {
+	Kind   string          `json:"kind"`
+	Item   models.ShopItem `json:"item"`
+	Points int             `json:"points"`
+}
+
+// State 一次检查的积分与商品状态
+type State struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Points    int       `json:"points"`
+	// Change 与上次检查相比的积分变化，没有上次记录时为 nil
+	Change *int   `json:"change,omitempty"`
+	Items  []Item `json:"items"`
+}
+
+// Result 积分商城检查任务的结果
+type Result struct {
+	State
+	Alerts    []Alert `json:"alerts"`
+	Delivered int     `json:"delivered"`
+	// DeliverError 部分渠道发送失败时的错误信息
+	DeliverError string `json:"deliver_error,omitempty"`
+
+	// record 本次检查的记录，由 Save 保存
+	record models.ShopRecord
+}
+
+// Point 积分余额历史中的一个点
+type Point struct {
+	Time   time.Time `json:"time"`
+	Points int       `json:"points"`
+}
+
+// Check 获取积分余额与商品列表，与上次记录比较得出关注商品的提醒
+//
+// 本次记录不会立即保存，否则提醒未送达时下次检查就比较不出这些变化；调用方应在提醒送达后调用 Save。
+// 读取上次记录失败时无法判断哪些变化已经提醒过，本次不产生提醒。
+func Check(ctx context.Context, svc *services.FF14Service) (*Result, error) {
+	balance, err := svc.PointsBalance(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if balance.Code != 10000 {
+		return nil, fmt.Errorf("获取积分余额失败: %d %s", balance.Code, balance.Msg)
+	}
+	items, err := svc.ShopItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if items.Code != 10000 {
+		return nil, fmt.Errorf("获取商品列表失败: %d %s", items.Code, items.Msg)
+	}
+
+	prev, err := svc.ShopLog().Last(config.DefaultAccount)
+	if err != nil {
+		slog.WarnContext(ctx, "⚠️ 读取积分记录失败，本次不发送提醒", "error", err)
+		prev = nil
+	}
+	cur := models.ShopRecord{
+		Time:    clock.Now(),
+		Account: config.DefaultAccount,
+		Points:  balance.Data.Points,
+		Items:   items.Data,
+	}
+	metrics.PointsBalance.Set(float64(cur.Points))
+
+	watch := config.Current().ShopWatchList()
+	res := &Result{
+		State:  NewState(cur, prev, watch),
+		Alerts: Diff(prev, cur, watch),
+		record: cur,
+	}
+	slog.InfoContext(ctx, "🛒 积分商城检查完成", "points", cur.Points, "items", len(cur.Items), "alerts", len(res.Alerts))
+	return res, nil
+}
+
+// Save 保存本次检查的记录，作为下次比较的基准
+func Save(svc *services.FF14Service, res *Result) error {
+	if err := svc.ShopLog().Append(res.record); err != nil {
+		return fmt.Errorf("保存积分记录失败: %w", err)
+	}
+	return nil
+}
+
+// Run 检查积分商城并发送关注商品的提醒
+//
+// 提醒在所有渠道都发送失败时不保存本次记录并返回错误，下次检查会重新提醒；部分渠道成功时照常保存，避免重复提醒。
+func Run(ctx context.Context, svc *services.FF14Service) (*Result, error) {
+	res, err := Check(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Alerts) > 0 {
+		n, err := Deliver(ctx, res.Alerts)
+		res.Delivered = n
+		if err != nil {
+			if n == 0 {
+				return nil, fmt.Errorf("发送积分商城提醒失败，下次检查时重试: %w", err)
+			}
+			res.DeliverError = err.Error()
+		}
+	}
+	if err := Save(svc, res); err != nil {
+		slog.WarnContext(ctx, "⚠️ 保存积分记录失败", "error", err)
+	}
+	return res, nil
+}
+
+// NewState 根据记录生成带关注与可兑换标记的状态，prev 为上一条记录
+func NewState(cur models.ShopRecord, prev *models.ShopRecord, watch []string) State {
+	st := State{
+		CheckedAt: cur.Time,
+		Points:    cur.Points,
+		Items:     make([]Item, 0, len(cur.Items)),
+	}
+	if prev != nil {
+		change := cur.Points - prev.Points
+		st.Change = &change
+	}
+	for _, it := range cur.Items {
+		st.Items = append(st.Items, Item{
+			ShopItem:   it,
+			Watched:    Watched(it, watch),
+			Affordable: affordable(it, cur.Points),
+		})
+	}
+	return st
+}
+
+// Diff 比较两次记录，返回关注商品的提醒
+//
+// 补货：上次库存为 0、本次有库存；可兑换：上次不可兑换（积分不足或新上架）而本次可兑换。
+// 同一商品同时满足时只提醒补货。没有上次记录时本次只作为基准，不产生提醒。
+func Diff(prev *models.ShopRecord, cur models.ShopRecord, watch []string) []Alert {
+	alerts := []Alert{}
+	if prev == nil {
+		return alerts
+	}
+	for _, it := range cur.Items {
+		if !Watched(it, watch) {
+			continue
+		}
+		var old *models.ShopItem
+		if i := slices.IndexFunc(prev.Items, func(p models.ShopItem) bool { return p.ID == it.ID }); i >= 0 {
+			old = &prev.Items[i]
+		}
+		switch {
+		case old != nil && old.Stock <= 0 && it.Stock > 0:
+			alerts = append(alerts, Alert{Kind: AlertRestock, Item: it, Points: cur.Points})
+		case affordable(it, cur.Points) && (old == nil || !affordable(*old, prev.Points)):
+			alerts = append(alerts, Alert{Kind: AlertAffordable, Item: it, Points: cur.Points})
+		}
+	}
+	return alerts
+}
+
+// Watched 商品是否在关注列表中，按 ID 或名称匹配
+func Watched(it models.ShopItem, watch []string) bool {
+	id := strconv.Itoa(it.ID)
+	return slices.ContainsFunc(watch, func(w string) bool { return w == id || w == it.Name })
+}
+
+// affordable 商品有库存且积分足够兑换
+func affordable(it models.ShopItem, points int) bool {
+	return it.Stock > 0 && points >= it.Cost
+}
+
+// Deliver 将提醒合并为一条 shop 通知发送
+func Deliver(ctx context.Context, alerts []Alert) (int, error) {
+	return notify.Send(ctx, notify.Message{
+		Event: notify.EventShop,
+		Title: "🛒 积分商城提醒",
+		Text:  Render(alerts),
+	})
+}
+
+// Render 提醒的文本形式
+func Render(alerts []Alert) string {
+	lines := make([]string, 0, len(alerts))
+	for _, a := range alerts {
+		tag := "可兑换"
+		if a.Kind == AlertRestock {
+			tag = "补货"
+		}
+		line := fmt.Sprintf("【%s】%s：%d 积分，库存 %d，当前积分 %d", tag, a.Item.Name, a.Item.Cost, a.Item.Stock, a.Points)
+		if a.Kind == AlertRestock && !affordable(a.Item, a.Points) {
+			line += fmt.Sprintf("，还差 %d", a.Item.Cost-a.Points)
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Latest 最近一次检查的状态，没有记录时返回 nil
+func Latest(log *services.ShopLog) (*State, error) {
+	var prev, last *models.ShopRecord
+	err := log.Each(func(rec models.ShopRecord) error {
+		if rec.Account == config.DefaultAccount {
+			prev, last = last, &rec
+		}
+		return nil
+	})
+	if err != nil || last == nil {
+		return nil, err
+	}
+	st := NewState(*last, prev, config.Current().ShopWatchList())
+	return &st, nil
+}
+
+// History 积分余额历史，from、to 为零值时不限制
+func History(log *services.ShopLog, from, to time.Time) ([]Point, error) {
+	points := []Point{}
+	err := log.Each(func(rec models.ShopRecord) error {
+		if rec.Account != config.DefaultAccount {
+			return nil
+		}
+		if (!from.IsZero() && rec.Time.Before(from)) || (!to.IsZero() && !rec.Time.Before(to)) {
+			return nil
+		}
+		points = append(points, Point{Time: rec.Time, Points: rec.Points})
+		return nil
+	})
+	return points, err
+}
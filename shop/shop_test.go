@@ -0,0 +1,52 @@
+package shop
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"llmaget/models"
+)
+
+func TestDiff(t *testing.T) {
+	watch := []string{"1", "鼠标垫", "陆行鸟<抱枕>"}
+	record := func(points int, items ...models.ShopItem) *models.ShopRecord {
+		return &models.ShopRecord{Points: points, Items: items}
+	}
+	pillow := func(stock int) models.ShopItem {
+		return models.ShopItem{ID: 1, Name: "陆行鸟<抱枕>", Cost: 150, Stock: stock}
+	}
+	pad := func(stock int) models.ShopItem {
+		return models.ShopItem{ID: 2, Name: "鼠标垫", Cost: 80, Stock: stock}
+	}
+	sticker := models.ShopItem{ID: 3, Name: "贴纸", Cost: 10, Stock: 50}
+
+	tests := []struct {
+		name string
+		prev *models.ShopRecord
+		cur  *models.ShopRecord
+		want []string
+	}{
+		{"没有上次记录只作为基准", nil, record(200, pillow(3), pad(2)), nil},
+		{"没有变化", record(200, pillow(3), pad(2)), record(200, pillow(3), pad(2)), nil},
+		{"补货", record(100, pillow(0), pad(2)), record(100, pillow(3), pad(2)), []string{"restock:1"}},
+		{"补货且可兑换只提醒补货", record(200, pillow(0)), record(200, pillow(3)), []string{"restock:1"}},
+		{"积分变得足够", record(100, pillow(3), pad(2)), record(160, pillow(3), pad(2)), []string{"affordable:1"}},
+		{"新上架且可兑换", record(100, pillow(3)), record(100, pillow(3), pad(2)), []string{"affordable:2"}},
+		{"新上架但积分不足", record(10, sticker), record(10, sticker, pad(2)), nil},
+		{"未关注的商品", record(5, sticker), record(100, sticker), nil},
+		{"售罄", record(200, pad(2)), record(200, pad(0)), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alerts := Diff(tt.prev, *tt.cur, watch)
+			got := make([]string, 0, len(alerts))
+			for _, a := range alerts {
+				got = append(got, a.Kind+":"+strconv.Itoa(a.Item.ID))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package ff14
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"llmaget/config"
+	"llmaget/jobs"
+	"llmaget/services"
+	"llmaget/shop"
+	"llmaget/sites"
+	"llmaget/workflow"
+)
+
+// Name 站点标识
+const Name = "ff14"
+
+// Adapter 石之家（FF14 社区）适配器
+//
+// 任务类型沿用 refresh、sign_in 等历史名称，与已有的任务历史和签到流水兼容。
+type Adapter struct {
+	svc       *services.FF14Service
+	workflows []*workflow.Definition
+}
+
+// New 创建石之家适配器，workflows 为以石之家 Cookie 执行的工作流
+func New(svc *services.FF14Service, workflows ...*workflow.Definition) *Adapter {
+	return &Adapter{svc: svc, workflows: workflows}
+}
+
+// Info 当前登录角色的基础信息
+type Info struct {
+	UUID            string `json:"uuid"`
+	CharacterName   string `json:"character_name"`
+	AreaName        string `json:"area_name"`
+	GroupName       string `json:"group_name"`
+	PlayTime        string `json:"play_time"`
+	PlayTimeMinutes int    `json:"play_time_minutes"`
+}
+
+// Name 站点标识
+func (a *Adapter) Name() string { return Name }
+
+// Title 展示名称
+func (a *Adapter) Title() string { return "石之家" }
+
+// Credentials Cookie 配置情况
+func (a *Adapter) Credentials() sites.Credentials {
+	state := config.GetState()
+	if !state.HasCookie() {
+		return sites.Credentials{}
+	}
+	return sites.Credentials{Configured: true, Hint: state.Effective().Cookie}
+}
+
+// CheckSession 获取当前登录用户信息以确认 Cookie 有效
+func (a *Adapter) CheckSession(ctx context.Context) error {
+	resp, err := a.svc.GetUserInfo(ctx, "")
+	if err != nil {
+		return err
+	}
+	if resp.Code != 10000 {
+		return fmt.Errorf("%w: %d %s", sites.ErrSessionExpired, resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// FetchInfo 获取当前登录角色的基础信息
+func (a *Adapter) FetchInfo(ctx context.Context) (any, error) {
+	resp, err := a.svc.GetUserInfo(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.Code != 10000 {
+		return nil, fmt.Errorf("获取信息失败: %d %s", resp.Code, resp.Msg)
+	}
+	info := &Info{
+		UUID:          resp.Data.UUID,
+		CharacterName: resp.Data.CharacterName,
+		AreaName:      resp.Data.AreaName,
+		GroupName:     resp.Data.GroupName,
+	}
+	if len(resp.Data.CharacterDetail) > 0 {
+		info.PlayTime = resp.Data.CharacterDetail[0].PlayTime
+		info.PlayTimeMinutes = services.ParsePlayTimeToMinutes(info.PlayTime)
+	}
+	return info, nil
+}
+
+// Tasks 数据刷新、签到、社区任务、积分商城检查、月末扫尾领取与工作流
+func (a *Adapter) Tasks() []sites.Task {
+	tasks := []sites.Task{
+		{
+			Kind:    jobs.KindRefresh,
+			Title:   "刷新角色信息",
+			OnStart: true,
+			Every:   func(st config.Settings) time.Duration { return st.FetchInterval.D() },
+			Run: func(ctx context.Context) (any, error) {
+				return nil, a.svc.SaveMyBaseInfo(ctx)
+			},
+		},
+		{
+			Kind:  jobs.KindSignIn,
+			Title: "签到",
+			Run: func(ctx context.Context) (any, error) {
+				return sites.JSONResult(a.svc.SignIn(ctx))
+			},
+		},
+		{
+			Kind:  jobs.KindSignAndClaim,
+			Title: "签到并领取奖励",
+			Every: func(st config.Settings) time.Duration { return st.SignInterval.D() },
+			Run: func(ctx context.Context) (any, error) {
+				return sites.JSONResult(a.svc.SignAndGetSignReward(ctx))
+			},
+		},
+		{
+			Kind:  jobs.KindMissions,
+			Title: "社区任务",
+			Every: func(st config.Settings) time.Duration { return st.SignInterval.D() },
+			Run: func(ctx context.Context) (any, error) {
+				return sites.JSONResult(a.svc.DoMissions(ctx))
+			},
+		},
+		{
+			Kind:  jobs.KindShopCheck,
+			Title: "积分商城检查",
+			Every: func(st config.Settings) time.Duration { return st.ShopInterval.D() },
+			Run: func(ctx context.Context) (any, error) {
+				return shop.Run(ctx, a.svc)
+			},
+		},
+		{
+			Kind:  jobs.KindRewardSweep,
+			Title: "月末扫尾领取",
+			Next:  nextSweep,
+			Run: func(ctx context.Context) (any, error) {
+				return sites.JSONResult(a.svc.SweepRewards(ctx))
+			},
+		},
+	}
+	for _, wf := range a.workflows {
+		tasks = append(tasks, a.workflowTask(wf))
+	}
+	return tasks
+}
+
+// workflowTask 将工作流包装为站点任务
+func (a *Adapter) workflowTask(wf *workflow.Definition) sites.Task {
+	t := sites.Task{
+		Kind:    wf.Kind(),
+		Title:   wf.Title,
+		OnStart: wf.OnStart,
+		Next:    wf.Next(),
+		Run: func(ctx context.Context) (any, error) {
+			return wf.Run(ctx, a.svc, false)
+		},
+	}
+	if d := wf.Interval(); d > 0 {
+		t.Every = func(config.Settings) time.Duration { return d }
+	}
+	return t
+}
+
+// 月末扫尾时间：每月最后一天 23:30 领取当月剩余奖励，次月 1 日 00:10 再补领上月
+const (
+	sweepMonthEndHour     = 23
+	sweepMonthEndMinute   = 30
+	sweepMonthStartHour   = 0
+	sweepMonthStartMinute = 10
+)
+
+// nextSweep 计算 now 之后最近的扫尾时间，按 now 所在时区（业务时区）划分月份
+func nextSweep(now time.Time) time.Time {
+	y, m, _ := now.Date()
+	loc := now.Location()
+	candidates := []time.Time{
+		time.Date(y, m, 1, sweepMonthStartHour, sweepMonthStartMinute, 0, 0, loc),
+		// 下月第 0 天即本月最后一天
+		time.Date(y, m+1, 0, sweepMonthEndHour, sweepMonthEndMinute, 0, 0, loc),
+		time.Date(y, m+1, 1, sweepMonthStartHour, sweepMonthStartMinute, 0, 0, loc),
+	}
+	for _, t := range candidates {
+		if t.After(now) {
+			return t
+		}
+	}
+	return candidates[len(candidates)-1]
+}
@@ -0,0 +1,37 @@
+package ff14
+
+import (
+	"testing"
+	"time"
+
+	"llmaget/clock"
+)
+
+func TestNextSweep(t *testing.T) {
+	sh := clock.Location()
+	at := func(y int, m time.Month, d, hh, mm int) time.Time {
+		return time.Date(y, m, d, hh, mm, 0, 0, sh)
+	}
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{"月中", at(2026, 1, 15, 12, 0), at(2026, 1, 31, 23, 30)},
+		{"月初补领前", at(2026, 2, 1, 0, 0), at(2026, 2, 1, 0, 10)},
+		{"月初补领时刻之后", at(2026, 2, 1, 0, 10), at(2026, 2, 28, 23, 30)},
+		{"月末扫尾前", at(2026, 2, 28, 23, 29), at(2026, 2, 28, 23, 30)},
+		{"月末扫尾后", at(2026, 2, 28, 23, 30), at(2026, 3, 1, 0, 10)},
+		{"闰年二月", at(2028, 2, 28, 23, 45), at(2028, 2, 29, 23, 30)},
+		{"跨年", at(2026, 12, 31, 23, 31), at(2027, 1, 1, 0, 10)},
+		// UTC 1 月 31 日 16:05 是上海 2 月 1 日 00:05，应按业务时区的 2 月计算
+		{"UTC 与业务时区不同月", time.Date(2026, 1, 31, 16, 5, 0, 0, time.UTC).In(sh), at(2026, 2, 1, 0, 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextSweep(tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextSweep(%s) = %s, want %s", tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package sites
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/clock"
+	"llmaget/config"
+	"llmaget/jobs"
+)
+
+var (
+	// ErrUnknownSite 站点未注册
+	ErrUnknownSite = errors.New("未知的站点")
+	// ErrUnknownTask 站点没有该任务
+	ErrUnknownTask = errors.New("未知的任务")
+	// ErrNoCredentials 站点未配置登录凭据
+	ErrNoCredentials = errors.New("未配置登录凭据")
+	// ErrSessionExpired 登录凭据已失效，需要重新获取
+	ErrSessionExpired = errors.New("登录已失效")
+)
+
+// Adapter 站点适配器，接入新站点的日常任务只需实现该接口并在启动时注册
+type Adapter interface {
+	// Name 站点标识，用于接口路径与配置中的 sites.<name>
+	Name() string
+	// Title 展示名称
+	Title() string
+	// Credentials 登录凭据的配置情况
+	Credentials() Credentials
+	// CheckSession 调用站点接口确认凭据仍然有效，失效时返回包装了 ErrSessionExpired 的错误
+	CheckSession(ctx context.Context) error
+	// FetchInfo 从站点获取账号基础信息
+	FetchInfo(ctx context.Context) (any, error)
+	// Tasks 站点的日常任务
+	Tasks() []Task
+}
+
+// Task 站点的日常任务，由任务管理器执行、调度器定时提交
+type Task struct {
+	// Kind 任务类型，在所有站点中唯一；新站点应以 "<站点>." 为前缀
+	Kind  string
+	Title string
+	Run   jobs.Func
+	// OnStart 服务启动时立即提交一次
+	OnStart bool
+	// Every 返回执行间隔，为 nil 时不按间隔执行
+	Every func(config.Settings) time.Duration
+	// Next 返回 now 之后的下次执行时间，为 nil 时不按日历执行
+	Next func(now time.Time) time.Time
+}
+
+// Schedule 任务调度方式的描述
+func (t Task) Schedule() string {
+	switch {
+	case t.Every != nil:
+		return "every " + t.Every(config.Current()).String()
+	case t.Next != nil:
+		return "next " + clock.FormatRFC3339(t.Next(clock.Now()))
+	default:
+		return "manual"
+	}
+}
+
+// Credentials 凭据配置情况
+type Credentials struct {
+	Configured bool `json:"configured"`
+	// Hint 脱敏后的凭据，便于确认配置的是哪一份
+	Hint string `json:"hint,omitempty"`
+}
+
+// Session 最近一次会话检查的结果
+type Session struct {
+	Valid     bool      `json:"valid"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// Status 站点状态
+type Status struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title"`
+	Enabled     bool         `json:"enabled"`
+	Credentials Credentials  `json:"credentials"`
+	Session     *Session     `json:"session,omitempty"`
+	Tasks       []TaskStatus `json:"tasks"`
+}
+
+// TaskStatus 任务信息
+type TaskStatus struct {
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	Schedule string `json:"schedule"`
+}
+
+// Registry 站点注册表
+type Registry struct {
+	adapters []Adapter
+
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+// NewRegistry 创建注册表，任务类型重复时 panic
+func NewRegistry(adapters ...Adapter) *Registry {
+	r := &Registry{sessions: make(map[string]Session)}
+	kinds := make(map[string]string)
+	for _, a := range adapters {
+		if _, ok := r.Get(a.Name()); ok {
+			panic("sites: 重复注册站点 " + a.Name())
+		}
+		for _, t := range a.Tasks() {
+			if owner, ok := kinds[t.Kind]; ok {
+				panic(fmt.Sprintf("sites: 任务 %s 已由站点 %s 注册", t.Kind, owner))
+			}
+			kinds[t.Kind] = a.Name()
+		}
+		r.adapters = append(r.adapters, a)
+	}
+	return r
+}
+
+// All 按注册顺序列出站点
+func (r *Registry) All() []Adapter {
+	return r.adapters
+}
+
+// Get 按名称查找站点
+func (r *Registry) Get(name string) (Adapter, bool) {
+	for _, a := range r.adapters {
+		if a.Name() == name {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// Task 查找站点的任务
+func (r *Registry) Task(site, kind string) (Adapter, Task, error) {
+	a, ok := r.Get(site)
+	if !ok {
+		return nil, Task{}, fmt.Errorf("%w: %s", ErrUnknownSite, site)
+	}
+	for _, t := range a.Tasks() {
+		if t.Kind == kind {
+			return a, t, nil
+		}
+	}
+	return nil, Task{}, fmt.Errorf("%w: %s/%s", ErrUnknownTask, site, kind)
+}
+
+// Enabled 站点是否启用，可通过配置 sites.<name>.disabled 停用
+func Enabled(a Adapter) bool {
+	return !config.GetState().Site(a.Name()).Disabled
+}
+
+// Ready 站点能否执行任务：已启用且配置了凭据
+func Ready(a Adapter) error {
+	if !Enabled(a) {
+		return fmt.Errorf("站点 %s 已停用", a.Name())
+	}
+	if !a.Credentials().Configured {
+		return fmt.Errorf("%s: %w", a.Title(), ErrNoCredentials)
+	}
+	return nil
+}
+
+// RegisterJobs 将所有站点的任务注册到任务管理器
+func (r *Registry) RegisterJobs(m *jobs.Manager) {
+	for _, a := range r.adapters {
+		for _, t := range a.Tasks() {
+			m.Register(t.Kind, t.Run)
+		}
+	}
+}
+
+// CheckSession 检查站点会话并记录结果
+func (r *Registry) CheckSession(ctx context.Context, a Adapter) Session {
+	s := Session{CheckedAt: clock.Now()}
+	if err := Ready(a); err != nil {
+		s.Error = err.Error()
+	} else if err := a.CheckSession(ctx); err != nil {
+		s.Error = err.Error()
+	} else {
+		s.Valid = true
+	}
+
+	r.mu.Lock()
+	r.sessions[a.Name()] = s
+	r.mu.Unlock()
+	return s
+}
+
+// Status 列出所有站点的状态，会话为最近一次检查的结果，不会请求站点
+func (r *Registry) Status() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]Status, 0, len(r.adapters))
+	for _, a := range r.adapters {
+		st := Status{
+			Name:        a.Name(),
+			Title:       a.Title(),
+			Enabled:     Enabled(a),
+			Credentials: a.Credentials(),
+		}
+		if s, ok := r.sessions[a.Name()]; ok {
+			st.Session = &s
+		}
+		for _, t := range a.Tasks() {
+			st.Tasks = append(st.Tasks, TaskStatus{Kind: t.Kind, Title: t.Title, Schedule: t.Schedule()})
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// JSONResult 将站点返回的 JSON 转换为可序列化的任务结果，无法解析时原样保存为字符串
+func JSONResult(body []byte, err error) (any, error) {
+	if err != nil {
+		return nil, err
+	}
+	var data any
+	if err := sonic.Unmarshal(body, &data); err != nil {
+		return string(body), nil
+	}
+	return data, nil
+}
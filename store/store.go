@@ -0,0 +1,234 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/bytedance/sonic"
+)
+
+// DefaultBackups 默认保留的备份数量
+const DefaultBackups = 3
+
+// ErrCorrupt 文件及其所有备份均不可用
+var ErrCorrupt = errors.New("文件已损坏且没有可用备份")
+
+// Validator 校验文件内容是否完整
+type Validator func(data []byte) error
+
+// ValidJSON 校验内容是否为合法 JSON
+func ValidJSON(data []byte) error {
+	if !sonic.Valid(data) {
+		return errors.New("不是合法的 JSON")
+	}
+	return nil
+}
+
+// BackupPath 第 n 个备份的路径，n 从 1 开始，1 为最新
+func BackupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// CorruptPath 从备份恢复时损坏主文件的保留路径
+func CorruptPath(path string) string {
+	return path + ".corrupt"
+}
+
+// Backups 列出存在的备份序号，从新到旧
+func Backups(path string, backups int) []int {
+	var out []int
+	for n := 1; n <= backups; n++ {
+		if _, err := os.Stat(BackupPath(path, n)); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// WriteFile 原子写入文件
+//
+// 先写入同目录下的临时文件并 fsync，再把当前文件轮转为备份，最后重命名覆盖，
+// 任何时刻崩溃都不会留下写了一半的目标文件。backups 为保留的备份数量，0 表示不备份。
+func WriteFile(path string, data []byte, perm os.FileMode, backups int) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("写入临时文件失败: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("同步临时文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("关闭临时文件失败: %w", err)
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return fmt.Errorf("设置文件权限失败: %w", err)
+	}
+
+	if backups > 0 {
+		if err := rotate(path, backups); err != nil {
+			slog.Warn("⚠️ 备份轮转失败", "file", path, "error", err)
+		}
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("替换文件失败: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// ReadFile 读取文件，主文件缺失或校验失败时依次尝试备份
+//
+// 从备份恢复成功时会把备份内容写回主文件，损坏的主文件另存为 CorruptPath 以便排查。
+// 主文件不存在且没有任何备份时返回 os.ErrNotExist。
+func ReadFile(path string, validate Validator, backups int) ([]byte, error) {
+	data, mainErr := os.ReadFile(path)
+	if mainErr == nil {
+		if mainErr = validate(data); mainErr == nil {
+			return data, nil
+		}
+	}
+
+	for n := 1; n <= backups; n++ {
+		bak, err := os.ReadFile(BackupPath(path, n))
+		if err != nil {
+			continue
+		}
+		if err := validate(bak); err != nil {
+			slog.Warn("⚠️ 备份文件已损坏，跳过", "file", BackupPath(path, n), "error", err)
+			continue
+		}
+
+		slog.Warn("⚠️ 文件不可用，已从备份恢复", "file", path, "backup", n, "error", mainErr)
+		if !errors.Is(mainErr, os.ErrNotExist) {
+			if err := os.Rename(path, CorruptPath(path)); err != nil {
+				slog.Warn("⚠️ 保留损坏文件失败", "file", path, "error", err)
+			}
+		}
+		if err := WriteFile(path, bak, 0644, 0); err != nil {
+			slog.Error("❌ 写回恢复内容失败", "file", path, "error", err)
+		}
+		return bak, nil
+	}
+
+	if errors.Is(mainErr, os.ErrNotExist) {
+		return nil, mainErr
+	}
+	return nil, fmt.Errorf("%w: %s: %v", ErrCorrupt, path, mainErr)
+}
+
+// Restore 用第 n 个备份覆盖主文件，覆盖前当前主文件会被轮转进备份
+func Restore(path string, n int, validate Validator, backups int) error {
+	data, err := os.ReadFile(BackupPath(path, n))
+	if err != nil {
+		return fmt.Errorf("读取备份失败: %w", err)
+	}
+	if err := validate(data); err != nil {
+		return fmt.Errorf("备份 %d 已损坏: %w", n, err)
+	}
+	return WriteFile(path, data, 0644, backups)
+}
+
+// rotate 将 path 轮转为 path.bak.1，原有备份依次后移，超出数量的丢弃
+func rotate(path string, backups int) error {
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for n := backups - 1; n >= 1; n-- {
+		from := BackupPath(path, n)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if err := os.Rename(from, BackupPath(path, n+1)); err != nil {
+			return err
+		}
+	}
+	// 复制而不是重命名，保证主文件在替换前始终存在
+	return copyFile(path, BackupPath(path, 1))
+}
+
+// copyFile 复制文件内容并 fsync
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// syncDir fsync 目录，确保重命名落盘
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return err
+	}
+	return nil
+}
+
+// AppendLine 向按行记录的文件追加一行并 fsync
+//
+// 上次追加中途崩溃留下的不完整行会先以换行结束，读取方应跳过无法解析的行。
+func AppendLine(path string, line []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, 0, len(line)+2)
+	if size := fi.Size(); size > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, size-1); err != nil {
+			return err
+		}
+		if last[0] != '\n' {
+			buf = append(buf, '\n')
+		}
+	}
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+	return f.Sync()
+}
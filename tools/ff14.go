@@ -0,0 +1,238 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bytedance/sonic"
+
+	"llmaget/config"
+	"llmaget/jobs"
+	"llmaget/services"
+	"llmaget/sites"
+)
+
+// signTimeout 签到工具等待任务完成的时限，超时后任务在后台继续执行
+const signTimeout = 60 * time.Second
+
+// Profile 精简后的角色资料，只保留对代理有用的字段
+type Profile struct {
+	UUID          string            `json:"uuid"`
+	CharacterName string            `json:"character_name"`
+	AreaName      string            `json:"area_name"`
+	GroupName     string            `json:"group_name"`
+	Profile       string            `json:"profile,omitempty"`
+	PlayTime      string            `json:"play_time,omitempty"`
+	CreateTime    string            `json:"create_time,omitempty"`
+	LastLoginTime string            `json:"last_login_time,omitempty"`
+	JobLevels     map[string]string `json:"job_levels,omitempty"`
+}
+
+// SignResult 签到工具的返回值
+type SignResult struct {
+	JobID   string   `json:"job_id,omitempty"`
+	Pending bool     `json:"pending,omitempty"`
+	Success []string `json:"success"`
+	Fail    []string `json:"fail"`
+	Claimed []string `json:"claimed"`
+}
+
+// NewRegistry 创建石之家工具注册表
+//
+// jobMgr 不为空时签到通过任务管理器执行，与定时签到互相合并；为空时（如 stdio 模式）直接调用服务。
+func NewRegistry(svc *services.FF14Service, jobMgr *jobs.Manager) *Registry {
+	r := &Registry{}
+
+	type searchArgs struct {
+		Name    string `json:"name"`
+		Server  string `json:"server"`
+		Refresh bool   `json:"refresh"`
+	}
+	r.Register(Tool{
+		Name:        "search_user",
+		Title:       "搜索角色",
+		Description: "按角色名搜索石之家用户，返回 UUID、服务器与大区。同名角色较多时应指定服务器。",
+		InputSchema: objectSchema(map[string]any{
+			"name":    map[string]any{"type": "string", "description": "角色名"},
+			"server":  map[string]any{"type": "string", "description": "服务器名，可选"},
+			"refresh": map[string]any{"type": "boolean", "description": "跳过缓存重新查询"},
+		}, "name"),
+		ReadOnly:   true,
+		Idempotent: true,
+		call: typed(func(ctx context.Context, a searchArgs) (any, error) {
+			return svc.SearchUser(ctx, a.Name, a.Server, a.Refresh)
+		}, func(a searchArgs) error {
+			if a.Name == "" {
+				return errors.New("name 不能为空")
+			}
+			return nil
+		}),
+	})
+
+	type profileArgs struct {
+		UUID    string `json:"uuid"`
+		Refresh bool   `json:"refresh"`
+	}
+	r.Register(Tool{
+		Name:        "get_profile",
+		Title:       "查询角色资料",
+		Description: "按 UUID 查询石之家角色资料，包括游戏时长与各职业等级。UUID 可通过 search_user 获取。",
+		InputSchema: objectSchema(map[string]any{
+			"uuid":    map[string]any{"type": "string", "description": "石之家用户 UUID"},
+			"refresh": map[string]any{"type": "boolean", "description": "跳过缓存重新查询"},
+		}, "uuid"),
+		ReadOnly:   true,
+		Idempotent: true,
+		call: typed(func(ctx context.Context, a profileArgs) (any, error) {
+			resp, err := svc.LookupProfile(ctx, a.UUID, a.Refresh)
+			if err != nil {
+				return nil, err
+			}
+			d := resp.Data
+			p := &Profile{
+				UUID:          d.UUID,
+				CharacterName: d.CharacterName,
+				AreaName:      d.AreaName,
+				GroupName:     d.GroupName,
+				Profile:       d.Profile,
+			}
+			if len(d.CharacterDetail) > 0 {
+				p.PlayTime = d.CharacterDetail[0].PlayTime
+				p.CreateTime = d.CharacterDetail[0].CreateTime
+				p.LastLoginTime = d.CharacterDetail[0].LastLoginTime
+			}
+			if len(d.CareerLevel) > 0 {
+				p.JobLevels = make(map[string]string, len(d.CareerLevel))
+				for _, c := range d.CareerLevel {
+					p.JobLevels[c.Career] = c.CharacterLevel
+				}
+			}
+			return p, nil
+		}, func(a profileArgs) error {
+			if a.UUID == "" {
+				return errors.New("uuid 不能为空")
+			}
+			return nil
+		}),
+	})
+
+	r.Register(Tool{
+		Name:        "get_play_time",
+		Title:       "查询游戏时长",
+		Description: "返回当前账号角色的游戏时长（分钟），数据来自最近一次定时获取。",
+		InputSchema: objectSchema(map[string]any{}),
+		ReadOnly:    true,
+		Idempotent:  true,
+		call: typed(func(context.Context, struct{}) (any, error) {
+			return svc.ParseFFInfo()
+		}, nil),
+	})
+
+	type rewardsArgs struct {
+		Month string `json:"month"`
+	}
+	r.Register(Tool{
+		Name:        "list_sign_rewards",
+		Title:       "查询签到奖励",
+		Description: "列出某月的签到奖励。is_get：1 已领取，0 可领取，-1 未达成。",
+		InputSchema: objectSchema(map[string]any{
+			"month": map[string]any{"type": "string", "description": "月份，格式 2006-01，默认当月", "pattern": `^\d{4}-\d{2}$`},
+		}),
+		ReadOnly:   true,
+		Idempotent: true,
+		call: typed(func(ctx context.Context, a rewardsArgs) (any, error) {
+			month, err := services.NormalizeMonth(a.Month)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+			}
+			rewards, err := svc.SignRewardList(ctx, month)
+			if err != nil {
+				return nil, err
+			}
+			if rewards.Code != 10000 {
+				return nil, fmt.Errorf("获取奖励列表失败: %s", rewards.Msg)
+			}
+			return map[string]any{"month": month, "rewards": rewards.Data}, nil
+		}, nil),
+	})
+
+	r.Register(Tool{
+		Name:        "sign_in_and_claim",
+		Title:       "签到并领取奖励",
+		Description: "为当前账号执行每日签到，并领取当月所有可领取的签到奖励。当天重复调用不会重复签到。",
+		InputSchema: objectSchema(map[string]any{}),
+		Idempotent:  true,
+		call: typed(func(ctx context.Context, _ struct{}) (any, error) {
+			if jobMgr == nil {
+				return signDirect(ctx, svc)
+			}
+			return submitSign(ctx, jobMgr)
+		}, nil),
+	})
+
+	return r
+}
+
+// submitSign 通过任务管理器签到并等待结果
+func submitSign(ctx context.Context, jobMgr *jobs.Manager) (any, error) {
+	job, _, err := jobMgr.Submit(ctx, jobs.KindSignAndClaim, config.DefaultAccount, jobs.TriggerAgent)
+	if err != nil {
+		return nil, fmt.Errorf("提交签到任务失败: %w", err)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, signTimeout)
+	defer cancel()
+	finished, err := jobMgr.Wait(waitCtx, job.ID)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &SignResult{JobID: job.ID, Pending: true}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if finished.Status == jobs.StatusFailed {
+		return nil, fmt.Errorf("签到失败: %s", finished.Error)
+	}
+	body, err := sonic.Marshal(finished.Result)
+	if err != nil {
+		return nil, err
+	}
+	res, err := signResult(body)
+	if err != nil {
+		return nil, err
+	}
+	res.JobID = job.ID
+	return res, nil
+}
+
+// signDirect 没有任务管理器时（MCP stdio）直接签到，仍追加签到流水
+func signDirect(ctx context.Context, svc *services.FF14Service) (any, error) {
+	ledger := jobs.NewLedger(config.GetState().Settings().LedgerFile())
+	var body []byte
+	_, err := ledger.Run(ctx, jobs.KindSignAndClaim, config.DefaultAccount, jobs.TriggerAgent, func(ctx context.Context) (any, error) {
+		var err error
+		body, err = svc.SignAndGetSignReward(ctx)
+		return sites.JSONResult(body, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return signResult(body)
+}
+
+// signResult 从 SignAndGetSignReward 的返回中提取领取结果
+func signResult(body []byte) (*SignResult, error) {
+	var m map[string][]string
+	if err := sonic.Unmarshal(body, &m); err != nil {
+		return nil, fmt.Errorf("解析签到结果失败: %w", err)
+	}
+	return &SignResult{Success: nonNil(m["success"]), Fail: nonNil(m["fail"]), Claimed: nonNil(m["claimed"])}, nil
+}
+
+// nonNil 保证空列表序列化为 []
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
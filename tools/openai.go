@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrNotAllowed 工具未在 agent_tools 中开放
+var ErrNotAllowed = errors.New("工具未开放给代理调用")
+
+// Function OpenAI 函数调用格式的工具定义
+type Function struct {
+	Type     string       `json:"type"`
+	Function FunctionSpec `json:"function"`
+}
+
+// FunctionSpec 函数名、说明与参数 JSON Schema
+type FunctionSpec struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// Function 转换为 OpenAI 函数定义，修改类工具在说明中注明
+func (t Tool) Function() Function {
+	desc := t.Description
+	if !t.ReadOnly {
+		desc += "（会修改账号状态）"
+	}
+	return Function{
+		Type:     "function",
+		Function: FunctionSpec{Name: t.Name, Description: desc, Parameters: t.InputSchema},
+	}
+}
+
+// Allowlist 代理可调用的工具，由 agent_tools 配置生成
+type Allowlist []string
+
+// Allows 是否允许调用该工具，未配置时只开放只读工具
+func (a Allowlist) Allows(t Tool) bool {
+	if len(a) == 0 {
+		return t.ReadOnly
+	}
+	return slices.Contains(a, "*") || slices.Contains(a, t.Name)
+}
+
+// Functions 列出允许代理调用的工具的函数定义
+func (r *Registry) Functions(allow Allowlist) []Function {
+	out := make([]Function, 0, len(r.tools))
+	for _, t := range r.tools {
+		if allow.Allows(t) {
+			out = append(out, t.Function())
+		}
+	}
+	return out
+}
+
+// CallAllowed 校验白名单后调用工具
+//
+// arguments 可以是 JSON 对象，也可以是 OpenAI 响应中 tool_calls[].function.arguments 那样的 JSON 字符串。
+func (r *Registry) CallAllowed(ctx context.Context, allow Allowlist, name string, arguments json.RawMessage) (any, error) {
+	t, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	if !allow.Allows(t) {
+		return nil, fmt.Errorf("%w: %s", ErrNotAllowed, name)
+	}
+	if len(arguments) > 0 && arguments[0] == '"' {
+		var s string
+		if err := json.Unmarshal(arguments, &s); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+		}
+		arguments = json.RawMessage(s)
+	}
+	return r.Call(ctx, name, arguments)
+}
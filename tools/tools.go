@@ -0,0 +1,112 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bytedance/sonic"
+)
+
+var (
+	// ErrUnknownTool 工具不存在
+	ErrUnknownTool = errors.New("未知的工具")
+	// ErrInvalidArguments 工具参数校验失败
+	ErrInvalidArguments = errors.New("参数错误")
+)
+
+// strictJSON 拒绝未知参数，避免模型拼错参数名时被静默忽略
+var strictJSON = sonic.Config{DisallowUnknownFields: true}.Froze()
+
+// Tool 可供 LLM 代理调用的操作
+type Tool struct {
+	Name        string
+	Title       string
+	Description string
+	// InputSchema 参数的 JSON Schema，类型必须为 object
+	InputSchema map[string]any
+	// ReadOnly 为 true 表示不修改账号状态；否则为修改类操作，可能需要单独授权
+	ReadOnly bool
+	// Idempotent 重复调用是否没有额外副作用
+	Idempotent bool
+
+	call func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+// Registry 工具注册表，MCP 与函数调用接口共用
+type Registry struct {
+	tools []Tool
+}
+
+// Register 注册工具，名称重复时 panic
+func (r *Registry) Register(t Tool) {
+	if _, ok := r.Get(t.Name); ok {
+		panic("tools: 重复注册 " + t.Name)
+	}
+	r.tools = append(r.tools, t)
+}
+
+// List 按注册顺序列出工具
+func (r *Registry) List() []Tool {
+	return r.tools
+}
+
+// Get 按名称查找工具
+func (r *Registry) Get(name string) (Tool, bool) {
+	for _, t := range r.tools {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tool{}, false
+}
+
+// Call 校验参数并调用工具，args 为 JSON 对象，可为空
+func (r *Registry) Call(ctx context.Context, name string, args json.RawMessage) (any, error) {
+	t, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTool, name)
+	}
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	return t.call(ctx, args)
+}
+
+// decodeArgs 严格解析参数到 dst
+func decodeArgs(args json.RawMessage, dst any) error {
+	if err := strictJSON.Unmarshal(args, dst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+	}
+	return nil
+}
+
+// objectSchema 构造 object 类型的 JSON Schema
+func objectSchema(properties map[string]any, required ...string) map[string]any {
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typed 将按参数类型编写的处理函数包装为通用调用，参数解析后先执行 validate
+func typed[A any](fn func(ctx context.Context, args A) (any, error), validate func(A) error) func(context.Context, json.RawMessage) (any, error) {
+	return func(ctx context.Context, raw json.RawMessage) (any, error) {
+		var args A
+		if err := decodeArgs(raw, &args); err != nil {
+			return nil, err
+		}
+		if validate != nil {
+			if err := validate(args); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidArguments, err)
+			}
+		}
+		return fn(ctx, args)
+	}
+}
@@ -0,0 +1,145 @@
+package workflow
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// segment JSONPath 的一级：对象字段、数组下标或通配符
+type segment struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Path 解析后的 JSONPath，支持 $、.key、['key']、[n]（负数从末尾计）与 [*] / .*
+type Path struct {
+	raw      string
+	segments []segment
+}
+
+// ParsePath 解析 JSONPath
+func ParsePath(raw string) (Path, error) {
+	p := Path{raw: raw}
+	s := strings.TrimSpace(raw)
+	if !strings.HasPrefix(s, "$") {
+		return p, fmt.Errorf("JSONPath 需以 $ 开头: %s", raw)
+	}
+	s = s[1:]
+	for s != "" {
+		switch {
+		case strings.HasPrefix(s, ".."):
+			return p, fmt.Errorf("JSONPath 不支持递归查找 ..: %s", raw)
+		case s[0] == '.':
+			s = s[1:]
+			end := strings.IndexAny(s, ".[")
+			if end < 0 {
+				end = len(s)
+			}
+			name := s[:end]
+			if name == "" {
+				return p, fmt.Errorf("JSONPath 字段名为空: %s", raw)
+			}
+			if name == "*" {
+				p.segments = append(p.segments, segment{wildcard: true})
+			} else {
+				p.segments = append(p.segments, segment{key: name})
+			}
+			s = s[end:]
+		case s[0] == '[':
+			end := strings.IndexByte(s, ']')
+			if end < 0 {
+				return p, fmt.Errorf("JSONPath 缺少 ]: %s", raw)
+			}
+			inner := strings.TrimSpace(s[1:end])
+			s = s[end+1:]
+			switch {
+			case inner == "*":
+				p.segments = append(p.segments, segment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				p.segments = append(p.segments, segment{key: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil {
+					return p, fmt.Errorf("JSONPath 下标无效 [%s]: %s", inner, raw)
+				}
+				p.segments = append(p.segments, segment{index: n, isIndex: true})
+			}
+		default:
+			return p, fmt.Errorf("JSONPath 语法错误，位置 %q: %s", s, raw)
+		}
+	}
+	return p, nil
+}
+
+// String 原始表达式
+func (p Path) String() string {
+	return p.raw
+}
+
+// Get 在 JSON 值上求值；路径中含通配符时返回所有匹配值组成的数组
+func (p Path) Get(v any) (any, error) {
+	values := []any{v}
+	multi := false
+	for _, seg := range p.segments {
+		var next []any
+		for _, cur := range values {
+			switch {
+			case seg.wildcard:
+				multi = true
+				switch c := cur.(type) {
+				case []any:
+					next = append(next, c...)
+				case map[string]any:
+					// 按字段名排序，保证结果顺序稳定
+					for _, k := range slices.Sorted(maps.Keys(c)) {
+						next = append(next, c[k])
+					}
+				default:
+					return nil, fmt.Errorf("%s: %T 不能使用通配符", p.raw, cur)
+				}
+			case seg.isIndex:
+				arr, ok := cur.([]any)
+				if !ok {
+					return nil, fmt.Errorf("%s: %T 不是数组", p.raw, cur)
+				}
+				i := seg.index
+				if i < 0 {
+					i += len(arr)
+				}
+				if i < 0 || i >= len(arr) {
+					if multi {
+						continue
+					}
+					return nil, fmt.Errorf("%s: 下标 %d 越界，数组长度 %d", p.raw, seg.index, len(arr))
+				}
+				next = append(next, arr[i])
+			default:
+				obj, ok := cur.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("%s: %T 不是对象，无法取字段 %s", p.raw, cur, seg.key)
+				}
+				item, ok := obj[seg.key]
+				if !ok {
+					if multi {
+						continue
+					}
+					return nil, fmt.Errorf("%s: 字段 %s 不存在", p.raw, seg.key)
+				}
+				next = append(next, item)
+			}
+		}
+		values = next
+	}
+	if multi {
+		if values == nil {
+			values = []any{}
+		}
+		return values, nil
+	}
+	return values[0], nil
+}
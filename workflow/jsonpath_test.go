@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		raw string
+		ok  bool
+	}{
+		{"$", true},
+		{"$.data.rows", true},
+		{" $.data[0].id ", true},
+		{"$['data'][\"rows\"][-1]", true},
+		{"$.data[*].id", true},
+		{"$.data.*", true},
+		{"data.rows", false},
+		{"$..id", false},
+		{"$.data.", false},
+		{"$.data[0", false},
+		{"$.data[x]", false},
+		{"$data", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			p, err := ParsePath(tt.raw)
+			if (err == nil) != tt.ok {
+				t.Fatalf("ParsePath(%q) error = %v, want ok %v", tt.raw, err, tt.ok)
+			}
+			if tt.ok && p.String() != tt.raw {
+				t.Errorf("String() = %q, want %q", p.String(), tt.raw)
+			}
+		})
+	}
+}
+
+func TestPathGet(t *testing.T) {
+	doc, err := decode([]byte(`{
+		"code": 10000,
+		"data": {
+			"rows": [
+				{"id": 1, "title": "a", "is_like": 0},
+				{"id": 2, "title": "b"},
+				{"id": 3, "title": "c", "is_like": 1}
+			],
+			"the key": "v"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want any
+		ok   bool
+	}{
+		{"根", "$.code", int64(10000), true},
+		{"嵌套字段", "$.data.rows[0].title", "a", true},
+		{"负数下标", "$.data.rows[-1].id", int64(3), true},
+		{"引号字段", "$.data['the key']", "v", true},
+		{"数组通配符", "$.data.rows[*].id", []any{int64(1), int64(2), int64(3)}, true},
+		{"通配符跳过缺失字段", "$.data.rows[*].is_like", []any{int64(0), int64(1)}, true},
+		{"对象通配符按字段名排序", "$.data.*", []any{doc.(map[string]any)["data"].(map[string]any)["rows"], "v"}, true},
+		{"通配符无匹配", "$.data.rows[*].missing", []any{}, true},
+		{"字段不存在", "$.data.missing", nil, false},
+		{"下标越界", "$.data.rows[3]", nil, false},
+		{"对数组取字段", "$.data.rows.id", nil, false},
+		{"对对象取下标", "$.data[0]", nil, false},
+		{"对标量使用通配符", "$.code[*]", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := ParsePath(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Get(doc)
+			if (err == nil) != tt.ok {
+				t.Fatalf("Get(%s) error = %v, want ok %v", tt.path, err, tt.ok)
+			}
+			if tt.ok && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get(%s) = %#v, want %#v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
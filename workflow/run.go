@@ -0,0 +1,418 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/bytedance/sonic"
+	"github.com/google/uuid"
+
+	"llmaget/clock"
+)
+
+// Client 工作流发送请求所需的能力，由 services.FF14Service 实现
+type Client interface {
+	// Request 以当前账号的 Cookie 与通用请求头发送请求，返回 HTTP 状态码与响应内容
+	Request(ctx context.Context, method, path string, query map[string]string, body []byte) (int, []byte, error)
+	// Pace 批量操作的请求间隔
+	Pace(ctx context.Context) error
+}
+
+// Result 工作流执行结果，与签到领奖的结果一样按状态列出名称
+type Result struct {
+	Success []string          `json:"success"`
+	Fail    []string          `json:"fail"`
+	Skipped []string          `json:"skipped"`
+	Outputs map[string]string `json:"outputs,omitempty"`
+}
+
+// funcs 模板可用的函数
+var funcs = template.FuncMap{
+	"uuid":  uuid.NewString,
+	"month": func() string { return clock.Now().Format("2006-01") },
+	"today": func() string { return clock.Now().Format("2006-01-02") },
+	"now":   clock.Now,
+	"json": func(v any) (string, error) {
+		data, err := sonic.Marshal(v)
+		return string(data), err
+	},
+	"has": func(m map[string]any, key string) bool {
+		_, ok := m[key]
+		return ok
+	},
+}
+
+// parseTemplate 编译模板，引用不存在的变量时报错
+func parseTemplate(name, text string) (*template.Template, error) {
+	t, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("模板 %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// parseTemplates 编译一组模板
+func parseTemplates(prefix string, texts map[string]string) (map[string]*template.Template, error) {
+	out := make(map[string]*template.Template, len(texts))
+	for k, text := range texts {
+		t, err := parseTemplate(prefix+"."+k, text)
+		if err != nil {
+			return nil, err
+		}
+		out[k] = t
+	}
+	return out, nil
+}
+
+// render 以 vars 渲染模板
+func render(t *template.Template, vars map[string]any) (string, error) {
+	var sb strings.Builder
+	if err := t.Execute(&sb, vars); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// renderBool 渲染条件模板，结果需为 true 或 false
+func renderBool(t *template.Template, vars map[string]any) (bool, error) {
+	s, err := render(t, vars)
+	if err != nil {
+		return false, err
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(s))
+	if err != nil {
+		return false, fmt.Errorf("模板 %s 的结果应为 true 或 false，实际为 %q", t.Name(), s)
+	}
+	return v, nil
+}
+
+// decode 解析 JSON 响应，整数统一转换为 int64，便于在模板中与整数字面量比较
+func decode(body []byte) (any, error) {
+	var v any
+	if err := sonic.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+	return normalize(v), nil
+}
+
+// normalize 将整数值的 float64 转换为 int64
+func normalize(v any) any {
+	switch x := v.(type) {
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return int64(x)
+		}
+	case []any:
+		for i := range x {
+			x[i] = normalize(x[i])
+		}
+	case map[string]any:
+		for k := range x {
+			x[k] = normalize(x[k])
+		}
+	}
+	return v
+}
+
+// runner 一次工作流执行的状态
+type runner struct {
+	def    *Definition
+	client Client
+	dryRun bool
+	sent   int
+	result *Result
+	// seeded 演练中已有步骤未发送，其提取变量为占位值
+	seeded bool
+}
+
+// Run 执行工作流；dryRun 时只发送 GET 请求，其余请求记为跳过
+//
+// 演练时未发送步骤的提取变量以占位值代替，引用占位值的请求同样不发送，
+// 因占位值无法渲染或求值的后续步骤记为跳过，不会终止演练。
+func (d *Definition) Run(ctx context.Context, c Client, dryRun bool) (*Result, error) {
+	slog.InfoContext(ctx, "📜 开始执行工作流", "workflow", d.Name, "dry_run", dryRun)
+
+	r := &runner{
+		def:    d,
+		client: c,
+		dryRun: dryRun,
+		result: &Result{Success: []string{}, Fail: []string{}, Skipped: []string{}},
+	}
+	// 初始变量之间不能相互引用，只能使用模板函数
+	vars := make(map[string]any, len(d.vars))
+	for k, t := range d.vars {
+		v, err := render(t, nil)
+		if err != nil {
+			return nil, fmt.Errorf("变量 %s: %w", k, err)
+		}
+		vars[k] = v
+	}
+
+	for _, s := range d.Steps {
+		var err error
+		if s.foreach != nil {
+			err = r.loop(ctx, s, vars)
+		} else {
+			err = r.single(ctx, s, vars)
+		}
+		if err != nil && r.dryRun && r.seeded {
+			slog.InfoContext(ctx, "🧪 演练模式，步骤依赖未发送的请求", "workflow", d.Name, "step", s.Name, "error", err)
+			r.skip(ctx, s, s.Name, "依赖演练未发送的步骤", s.Label != "" || s.foreach != nil)
+			r.seed(s, vars)
+			continue
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "❌ 工作流终止", "workflow", d.Name, "step", s.Name, "error", err)
+			return nil, fmt.Errorf("步骤 %s: %w", s.Name, err)
+		}
+	}
+
+	if len(d.result) > 0 {
+		r.result.Outputs = make(map[string]string, len(d.result))
+		for k, t := range d.result {
+			v, err := render(t, vars)
+			if err != nil {
+				return nil, fmt.Errorf("结果 %s: %w", k, err)
+			}
+			r.result.Outputs[k] = v
+		}
+	}
+
+	slog.InfoContext(ctx, "✅ 工作流执行完成", "workflow", d.Name,
+		"success", len(r.result.Success), "fail", len(r.result.Fail), "skipped", len(r.result.Skipped))
+	return r.result, nil
+}
+
+// single 执行非循环步骤；有 label 的步骤计入结果汇总，没有 label 的步骤失败时终止工作流
+func (r *runner) single(ctx context.Context, s *Step, vars map[string]any) error {
+	report := s.Label != ""
+	label, err := r.label(s, vars, s.Name)
+	if err != nil {
+		return err
+	}
+	if s.when != nil {
+		ok, err := renderBool(s.when, vars)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			r.skip(ctx, s, label, "条件不满足", report)
+			return nil
+		}
+	}
+
+	resp, err := r.request(ctx, s, vars)
+	if err == nil {
+		for name, p := range s.extract {
+			v, perr := p.Get(resp)
+			if perr != nil {
+				err = fmt.Errorf("提取 %s: %w", name, perr)
+				break
+			}
+			vars[name] = v
+		}
+	}
+	switch {
+	case errors.Is(err, errDryRun):
+		r.seed(s, vars)
+		r.skip(ctx, s, label, "演练未发送", report)
+	case err != nil && !report:
+		return err
+	case err != nil:
+		r.fail(ctx, s, label, err)
+	case report:
+		r.result.Success = append(r.result.Success, label)
+	}
+	return nil
+}
+
+// loop 对 foreach 的结果逐项执行步骤，单项失败时记录并继续
+func (r *runner) loop(ctx context.Context, s *Step, vars map[string]any) error {
+	v, err := s.foreach.Get(vars)
+	if err != nil {
+		return fmt.Errorf("foreach: %w", err)
+	}
+	items, ok := v.([]any)
+	if !ok {
+		return fmt.Errorf("foreach: %s 的结果不是数组", s.foreach)
+	}
+
+	collected := make(map[string][]any, len(s.extract))
+	for name := range s.extract {
+		collected[name] = []any{}
+	}
+	sent := 0
+	for i, item := range items {
+		if s.Limit > 0 && sent >= s.Limit {
+			slog.InfoContext(ctx, "⏭️ 已达到单次上限", "workflow", r.def.Name, "step", s.Name, "limit", s.Limit)
+			break
+		}
+		scope := maps.Clone(vars)
+		scope[s.As] = item
+		scope["index"] = i
+
+		label, err := r.label(s, scope, fmt.Sprintf("%s #%d", s.Name, i+1))
+		if err != nil {
+			return err
+		}
+		if s.when != nil {
+			ok, err := renderBool(s.when, scope)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				r.skip(ctx, s, label, "条件不满足", true)
+				continue
+			}
+		}
+
+		sent++
+		resp, err := r.request(ctx, s, scope)
+		if errors.Is(err, errDryRun) {
+			r.skip(ctx, s, label, "演练未发送", true)
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			r.fail(ctx, s, label, err)
+			continue
+		}
+		for name, p := range s.extract {
+			if v, err := p.Get(resp); err == nil {
+				collected[name] = append(collected[name], v)
+			}
+		}
+		r.result.Success = append(r.result.Success, label)
+	}
+	for name, values := range collected {
+		vars[name] = values
+	}
+	return nil
+}
+
+// errDryRun 演练模式下未发送的请求
+var errDryRun = errors.New("演练未发送")
+
+// dryRunMark 演练占位值的前缀
+const dryRunMark = "<演练未发送:"
+
+// seed 演练时为未发送步骤的提取变量填入占位值，供后续模板渲染
+func (r *runner) seed(s *Step, vars map[string]any) {
+	for name := range s.extract {
+		vars[name] = dryRunMark + name + ">"
+	}
+	if len(s.extract) > 0 {
+		r.seeded = true
+	}
+}
+
+// request 渲染并发送步骤的请求，返回解析后的响应；响应不满足成功条件时返回错误
+func (r *runner) request(ctx context.Context, s *Step, vars map[string]any) (any, error) {
+	path, err := render(s.path, vars)
+	if err != nil {
+		return nil, err
+	}
+	query := make(map[string]string, len(s.query))
+	for k, t := range s.query {
+		if query[k], err = render(t, vars); err != nil {
+			return nil, err
+		}
+	}
+	var body []byte
+	if s.body != nil {
+		text, err := render(s.body, vars)
+		if err != nil {
+			return nil, err
+		}
+		if !sonic.ValidString(text) {
+			return nil, fmt.Errorf("请求体不是有效的 JSON: %s", text)
+		}
+		body = []byte(text)
+	}
+	if r.dryRun && r.seeded && strings.Contains(path+fmt.Sprint(query)+string(body), dryRunMark) {
+		slog.InfoContext(ctx, "🧪 演练模式，请求引用了未发送步骤的结果", "workflow", r.def.Name, "step", s.Name, "method", s.Method, "path", path)
+		return nil, errDryRun
+	}
+	if r.dryRun && s.Method != "GET" {
+		slog.InfoContext(ctx, "🧪 演练模式，跳过请求", "workflow", r.def.Name, "step", s.Name, "method", s.Method, "path", path, "body", string(body))
+		return nil, errDryRun
+	}
+
+	if r.sent > 0 {
+		if err := r.client.Pace(ctx); err != nil {
+			return nil, err
+		}
+	}
+	r.sent++
+	status, raw, err := r.client.Request(ctx, s.Method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := decode(raw)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP %d，响应不是 JSON: %w", status, err)
+	}
+
+	ok := status >= 200 && status < 300
+	if ok {
+		if s.success != nil {
+			scope := maps.Clone(vars)
+			scope["resp"] = resp
+			scope["status"] = status
+			if ok, err = renderBool(s.success, scope); err != nil {
+				return nil, err
+			}
+		} else if obj, isObj := resp.(map[string]any); isObj {
+			ok = obj["code"] == int64(10000)
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("HTTP %d: %s", status, summarize(resp))
+	}
+	return resp, nil
+}
+
+// summarize 从失败响应中提取业务码与提示信息
+func summarize(resp any) string {
+	if obj, ok := resp.(map[string]any); ok {
+		if msg, ok := obj["msg"]; ok {
+			return fmt.Sprintf("%v %v", obj["code"], msg)
+		}
+	}
+	data, _ := sonic.Marshal(resp)
+	if len(data) > 200 {
+		data = data[:200]
+	}
+	return string(data)
+}
+
+// label 渲染结果汇总中的名称
+func (r *runner) label(s *Step, vars map[string]any, fallback string) (string, error) {
+	if s.label == nil {
+		return fallback, nil
+	}
+	return render(s.label, vars)
+}
+
+// skip 记录跳过的项，report 为 false 时只输出日志
+func (r *runner) skip(ctx context.Context, s *Step, label, reason string, report bool) {
+	slog.InfoContext(ctx, "⏭️ 跳过", "workflow", r.def.Name, "step", s.Name, "item", label, "reason", reason)
+	if report {
+		r.result.Skipped = append(r.result.Skipped, label)
+	}
+}
+
+// fail 记录失败的项
+func (r *runner) fail(ctx context.Context, s *Step, label string, err error) {
+	slog.WarnContext(ctx, "⚠️ 执行失败", "workflow", r.def.Name, "step", s.Name, "item", label, "error", err)
+	r.result.Fail = append(r.result.Fail, label)
+}
@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeClient 按路径返回固定响应并记录请求的测试客户端
+type fakeClient struct {
+	responses map[string]string
+	requests  []string
+	paced     int
+}
+
+func (c *fakeClient) Request(_ context.Context, method, path string, query map[string]string, body []byte) (int, []byte, error) {
+	c.requests = append(c.requests, method+" "+path+" "+string(body))
+	if resp, ok := c.responses[path]; ok {
+		return 200, []byte(resp), nil
+	}
+	return 404, []byte(`{"code":404,"msg":"not found"}`), nil
+}
+
+func (c *fakeClient) Pace(context.Context) error {
+	c.paced++
+	return nil
+}
+
+// testWorkflow 获取列表后逐项点赞，再按 POST 步骤提取的编号领取
+const testWorkflow = `
+name: daily_like
+steps:
+  - name: posts
+    path: /posts
+    extract:
+      posts: $.data.rows
+  - name: like
+    foreach: $.posts
+    as: post
+    limit: 2
+    when: '{{ eq .post.is_like 0 }}'
+    label: '{{ .post.title }}'
+    method: POST
+    path: /like
+    body: '{"id": {{ json .post.id }}}'
+  - name: task
+    method: POST
+    path: /task
+    body: '{}'
+    extract:
+      task: $.data.id
+  - name: claim
+    label: 领取 {{ .task }}
+    path: /claim/{{ .task }}
+  - name: detail
+    foreach: $.task.items
+    label: '{{ .item }}'
+    path: /detail
+result:
+  total: '{{ len .posts }}'
+  task: '{{ .task }}'
+`
+
+func newTestClient() *fakeClient {
+	return &fakeClient{responses: map[string]string{
+		"/posts": `{"code":10000,"data":{"rows":[
+			{"id":1,"title":"a","is_like":0},
+			{"id":2,"title":"b","is_like":1},
+			{"id":3,"title":"c","is_like":0},
+			{"id":4,"title":"d","is_like":0}
+		]}}`,
+		"/like":    `{"code":10000}`,
+		"/task":    `{"code":10000,"data":{"id":7}}`,
+		"/claim/7": `{"code":10000}`,
+		"/detail":  `{"code":10000}`,
+	}}
+}
+
+func TestDefinitionRun(t *testing.T) {
+	def, err := Parse([]byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// foreach 的结果不是数组时终止工作流
+	if _, err := def.Run(context.Background(), newTestClient(), false); err == nil || !strings.Contains(err.Error(), "步骤 detail") {
+		t.Fatalf("Run() error = %v, want 步骤 detail 失败", err)
+	}
+
+	def.Steps = def.Steps[:4]
+	c := newTestClient()
+	res, err := def.Run(context.Background(), c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c", "领取 7"}; !slices.Equal(res.Success, want) {
+		t.Errorf("Success = %v, want %v", res.Success, want)
+	}
+	if want := []string{"b"}; !slices.Equal(res.Skipped, want) || len(res.Fail) != 0 {
+		t.Errorf("Skipped = %v Fail = %v, want %v []", res.Skipped, res.Fail, want)
+	}
+	if res.Outputs["total"] != "4" || res.Outputs["task"] != "7" {
+		t.Errorf("Outputs = %v", res.Outputs)
+	}
+	want := []string{"GET /posts ", `POST /like {"id": 1}`, `POST /like {"id": 3}`, "POST /task {}", "GET /claim/7 "}
+	if !slices.Equal(c.requests, want) {
+		t.Errorf("requests = %q, want %q", c.requests, want)
+	}
+	if c.paced != len(want)-1 {
+		t.Errorf("Pace 调用 %d 次, want %d", c.paced, len(want)-1)
+	}
+}
+
+func TestDefinitionRunFailure(t *testing.T) {
+	def, err := Parse([]byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	def.Steps = def.Steps[:4]
+	c := newTestClient()
+	c.responses["/like"] = `{"code":20001,"msg":"too fast"}`
+	c.responses["/claim/7"] = `{"code":10001,"msg":"已领取"}`
+
+	res, err := def.Run(context.Background(), c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c", "领取 7"}; !slices.Equal(res.Fail, want) || len(res.Success) != 0 {
+		t.Errorf("Fail = %v Success = %v, want %v []", res.Fail, res.Success, want)
+	}
+
+	// 没有 label 的非循环步骤失败时终止工作流
+	c = newTestClient()
+	c.responses["/task"] = `{"code":10103,"msg":"请先登录"}`
+	if _, err := def.Run(context.Background(), c, false); err == nil || !strings.Contains(err.Error(), "10103 请先登录") {
+		t.Errorf("Run() error = %v, want 步骤 task 失败", err)
+	}
+}
+
+func TestDefinitionRunDryRun(t *testing.T) {
+	def, err := Parse([]byte(testWorkflow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestClient()
+	res, err := def.Run(context.Background(), c, true)
+	if err != nil {
+		t.Fatalf("演练不应因未发送步骤的提取变量终止: %v", err)
+	}
+
+	// 只发送不依赖未发送步骤的 GET 请求
+	if want := []string{"GET /posts "}; !slices.Equal(c.requests, want) {
+		t.Errorf("requests = %q, want %q", c.requests, want)
+	}
+	if want := []string{"a", "b", "c", "领取 <演练未发送:task>", "detail"}; !slices.Equal(res.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", res.Skipped, want)
+	}
+	if len(res.Success) != 0 || len(res.Fail) != 0 {
+		t.Errorf("Success = %v Fail = %v, 演练时应为空", res.Success, res.Fail)
+	}
+	if res.Outputs["task"] != "<演练未发送:task>" {
+		t.Errorf("Outputs = %v", res.Outputs)
+	}
+}
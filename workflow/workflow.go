@@ -0,0 +1,288 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// KindPrefix 工作流任务类型前缀，任务类型为 workflow.<name>
+const KindPrefix = "workflow."
+
+// ErrInvalidDefinition 工作流定义无效
+var ErrInvalidDefinition = errors.New("工作流定义无效")
+
+// namePattern 工作流与步骤名称
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// methods 支持的请求方法
+var methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// Definition 工作流定义，从 workflow_dir 下的 YAML 文件加载
+type Definition struct {
+	// Name 工作流标识，只能包含小写字母、数字与下划线
+	Name  string `yaml:"name" json:"name"`
+	Title string `yaml:"title" json:"title"`
+	// OnStart 服务启动时执行一次
+	OnStart bool `yaml:"on_start" json:"on_start,omitempty"`
+	// Every 执行间隔，如 24h；与 Daily 二选一，都为空时只能手动执行
+	Every string `yaml:"every" json:"every,omitempty"`
+	// Daily 每天的执行时间（业务时区），如 09:30
+	Daily string `yaml:"daily" json:"daily,omitempty"`
+	// Vars 初始变量，值为模板
+	Vars map[string]string `yaml:"vars" json:"vars,omitempty"`
+	// Steps 按顺序执行的请求步骤
+	Steps []*Step `yaml:"steps" json:"steps"`
+	// Result 结果映射，值为模板，渲染结果放入 outputs
+	Result map[string]string `yaml:"result" json:"result,omitempty"`
+
+	File string `yaml:"-" json:"file"`
+
+	every  time.Duration
+	daily  time.Duration
+	vars   map[string]*template.Template
+	result map[string]*template.Template
+}
+
+// Step 一次请求，可对数组变量逐项执行
+type Step struct {
+	Name   string `yaml:"name" json:"name"`
+	Method string `yaml:"method" json:"method,omitempty"`
+	// Path 以 / 开头的接口路径，可使用模板
+	Path  string            `yaml:"path" json:"path"`
+	Query map[string]string `yaml:"query" json:"query,omitempty"`
+	// Body JSON 请求体模板，如 {"id": {{ json .post.id }}}
+	Body string `yaml:"body" json:"body,omitempty"`
+
+	// Foreach 对变量求值的 JSONPath，结果为数组时逐项执行，当前项保存在 As 指定的变量中（默认 item）
+	Foreach string `yaml:"foreach" json:"foreach,omitempty"`
+	As      string `yaml:"as" json:"as,omitempty"`
+	// Limit 循环中最多发送的请求数，0 表示不限
+	Limit int `yaml:"limit" json:"limit,omitempty"`
+	// When 条件模板，结果为 false 时跳过
+	When string `yaml:"when" json:"when,omitempty"`
+	// Label 结果汇总中的名称模板；非循环步骤没有 Label 时失败会终止工作流
+	Label string `yaml:"label" json:"label,omitempty"`
+	// Success 判断成功的模板，可使用 .resp（响应 JSON）与 .status；默认 HTTP 2xx 且业务码为 10000
+	Success string `yaml:"success" json:"success,omitempty"`
+	// Extract 从响应中提取变量，值为以响应为根的 JSONPath；循环中提取结果按项收集为数组
+	Extract map[string]string `yaml:"extract" json:"extract,omitempty"`
+
+	path    *template.Template
+	query   map[string]*template.Template
+	body    *template.Template
+	foreach *Path
+	when    *template.Template
+	label   *template.Template
+	success *template.Template
+	extract map[string]Path
+}
+
+// Kind 工作流对应的任务类型
+func (d *Definition) Kind() string {
+	return KindPrefix + d.Name
+}
+
+// Interval 执行间隔，为 0 时不按间隔执行
+func (d *Definition) Interval() time.Duration {
+	return d.every
+}
+
+// Next 按 Daily 计算 now 之后的下次执行时间，未配置 Daily 时为 nil
+func (d *Definition) Next() func(time.Time) time.Time {
+	if d.Daily == "" {
+		return nil
+	}
+	return func(now time.Time) time.Time {
+		y, m, day := now.Date()
+		at := time.Date(y, m, day, 0, 0, 0, 0, now.Location()).Add(d.daily)
+		if !at.After(now) {
+			at = at.AddDate(0, 0, 1)
+		}
+		return at
+	}
+}
+
+// Parse 解析并校验工作流定义
+func Parse(data []byte) (*Definition, error) {
+	var d Definition
+	if err := yaml.UnmarshalWithOptions(data, &d, yaml.Strict(), yaml.DisallowUnknownField()); err != nil {
+		return nil, fmt.Errorf("%w: 解析失败: %w", ErrInvalidDefinition, err)
+	}
+	if err := d.compile(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDefinition, err)
+	}
+	return &d, nil
+}
+
+// Load 加载目录下的全部工作流定义，目录不存在时返回空
+//
+// 单个文件无效时跳过该文件，错误合并后与其余定义一起返回。
+func Load(dir string) ([]*Definition, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取工作流目录失败: %w", err)
+	}
+
+	var defs []*Definition
+	var errs []error
+	seen := make(map[string]string)
+	for _, e := range entries {
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		if e.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		file := filepath.Join(dir, e.Name())
+		data, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		d, err := Parse(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name(), err))
+			continue
+		}
+		if prev, ok := seen[d.Name]; ok {
+			errs = append(errs, fmt.Errorf("%s: %w: 名称 %s 与 %s 重复", e.Name(), ErrInvalidDefinition, d.Name, prev))
+			continue
+		}
+		seen[d.Name] = e.Name()
+		d.File = file
+		defs = append(defs, d)
+	}
+	return defs, errors.Join(errs...)
+}
+
+// compile 校验定义并预编译模板与 JSONPath
+func (d *Definition) compile() error {
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("name 只能包含小写字母、数字与下划线: %q", d.Name)
+	}
+	if d.Title == "" {
+		d.Title = d.Name
+	}
+	if d.Every != "" && d.Daily != "" {
+		return fmt.Errorf("every 与 daily 只能设置一个")
+	}
+	if d.Every != "" {
+		v, err := time.ParseDuration(d.Every)
+		if err != nil || v < time.Minute {
+			return fmt.Errorf("every 需为不小于 1m 的时长，如 24h: %q", d.Every)
+		}
+		d.every = v
+	}
+	if d.Daily != "" {
+		t, err := time.Parse("15:04", d.Daily)
+		if err != nil {
+			return fmt.Errorf("daily 需为 HH:MM 形式: %q", d.Daily)
+		}
+		d.daily = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	if len(d.Steps) == 0 {
+		return fmt.Errorf("steps 不能为空")
+	}
+
+	var err error
+	if d.vars, err = parseTemplates(d.Name+".vars", d.Vars); err != nil {
+		return err
+	}
+	if d.result, err = parseTemplates(d.Name+".result", d.Result); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for i, s := range d.Steps {
+		if s == nil {
+			return fmt.Errorf("第 %d 个步骤为空", i+1)
+		}
+		if err := s.compile(d.Name); err != nil {
+			return fmt.Errorf("步骤 %q: %w", s.Name, err)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("步骤名称 %q 重复", s.Name)
+		}
+		names[s.Name] = true
+	}
+	return nil
+}
+
+// compile 校验步骤并预编译模板与 JSONPath
+func (s *Step) compile(prefix string) error {
+	if !namePattern.MatchString(s.Name) {
+		return fmt.Errorf("name 只能包含小写字母、数字与下划线")
+	}
+	prefix += "." + s.Name
+	if s.Method == "" {
+		s.Method = "GET"
+	}
+	s.Method = strings.ToUpper(s.Method)
+	if !slices.Contains(methods, s.Method) {
+		return fmt.Errorf("method 只能为 %s", strings.Join(methods, "/"))
+	}
+	if !strings.HasPrefix(s.Path, "/") {
+		return fmt.Errorf("path 需以 / 开头")
+	}
+	if s.Limit < 0 {
+		return fmt.Errorf("limit 不能小于 0")
+	}
+	if s.Foreach == "" && (s.As != "" || s.Limit > 0) {
+		return fmt.Errorf("as 与 limit 只能用于 foreach 步骤")
+	}
+	if s.As == "" {
+		s.As = "item"
+	}
+
+	var err error
+	if s.path, err = parseTemplate(prefix+".path", s.Path); err != nil {
+		return err
+	}
+	if s.query, err = parseTemplates(prefix+".query", s.Query); err != nil {
+		return err
+	}
+	for name, raw := range map[string]string{"body": s.Body, "when": s.When, "label": s.Label, "success": s.Success} {
+		if raw == "" {
+			continue
+		}
+		t, err := parseTemplate(prefix+"."+name, raw)
+		if err != nil {
+			return err
+		}
+		switch name {
+		case "body":
+			s.body = t
+		case "when":
+			s.when = t
+		case "label":
+			s.label = t
+		case "success":
+			s.success = t
+		}
+	}
+	if s.Foreach != "" {
+		p, err := ParsePath(s.Foreach)
+		if err != nil {
+			return fmt.Errorf("foreach: %w", err)
+		}
+		s.foreach = &p
+	}
+	s.extract = make(map[string]Path, len(s.Extract))
+	for name, raw := range s.Extract {
+		p, err := ParsePath(raw)
+		if err != nil {
+			return fmt.Errorf("extract.%s: %w", name, err)
+		}
+		s.extract[name] = p
+	}
+	return nil
+}